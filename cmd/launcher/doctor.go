@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"task-runner-launcher/internal/commands"
+	"task-runner-launcher/internal/logs"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// doctor diagnoses the launcher's deployment for the given runner types, or for
+// all runner types in the config file if none are given, and prints a report of
+// passed and failed checks, as JSON with `--json`. Returns the exit code.
+func doctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	_ = flags.Parse(args) // exits on error
+
+	if *asJSON {
+		logs.SetOutput(os.Stderr) // keep stdout machine-readable
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	cmd := commands.NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, ""))
+	report := cmd.Execute(ctx, flags.Args(), envconfig.OsLookuper())
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			logs.Errorf("Failed to encode doctor report: %v", err)
+			return 1
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if !report.Passed {
+		return 1
+	}
+
+	return 0
+}
+
+func printDoctorReport(report commands.DoctorReport) {
+	indent := strings.Repeat(" ", 39) // aligns with details
+
+	failed := 0
+	for _, check := range report.Checks {
+		result := "PASS"
+		if !check.Passed {
+			result = "FAIL"
+			failed++
+		}
+
+		name := check.Name
+		if check.RunnerType != "" {
+			name = fmt.Sprintf("%s [%s]", check.Name, check.RunnerType)
+		}
+		if check.Broker != "" {
+			name = fmt.Sprintf("%s [%s]", check.Name, check.Broker)
+		}
+
+		detail := strings.ReplaceAll(check.Detail, "\n", "\n"+indent)
+		fmt.Printf("%s  %-32s %s\n", result, name, detail)
+		if len(check.Env) > 0 {
+			fmt.Printf("%s%s\n", indent, strings.Join(check.Env, ", "))
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d of %d check(s) failed\n", failed, len(report.Checks))
+		return
+	}
+
+	fmt.Printf("\nAll %d check(s) passed\n", len(report.Checks))
+}
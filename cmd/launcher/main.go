@@ -1,61 +1,222 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
 	"sync"
+	"syscall"
+	"task-runner-launcher/internal/cgroup"
 	"task-runner-launcher/internal/commands"
 	"task-runner-launcher/internal/config"
 	"task-runner-launcher/internal/errorreporting"
 	"task-runner-launcher/internal/http"
 	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/process"
+	"task-runner-launcher/internal/shim"
+	"task-runner-launcher/internal/status"
+	"time"
 
 	"github.com/sethvargo/go-envconfig"
 )
 
+const (
+	// healthCheckServerShutdownTimeout is the max time to wait for the launcher's
+	// health check server to finish serving in-flight requests on shutdown.
+	healthCheckServerShutdownTimeout = 2 * time.Second
+
+	// configPollInterval is how often the launcher checks the config file for
+	// changes to reload. A change is reloaded once it has settled for one more
+	// interval, i.e. within two intervals.
+	configPollInterval = 1 * time.Second
+)
+
+var initCgroupsOnce sync.Once
+
 func main() {
+	if shim.IsShim() {
+		err := shim.Run()
+		fmt.Fprintf(os.Stderr, "Failed to set up runner process: %v\n", err)
+		os.Exit(shim.ExitCodeSetupFailed)
+	}
+
 	flag.Usage = func() {
-		fmt.Printf("Usage: %s [runner-type(s)]\n", os.Args[0])
+		fmt.Printf("Usage:\n")
+		fmt.Printf("  %s [launch] <runner-type(s)>\t\tLaunch and manage runners of the given type(s)\n", os.Args[0])
+		fmt.Printf("  %s validate-config [runner-type(s)]\tValidate the config for the given or all runner type(s)\n", os.Args[0])
+		fmt.Printf("  %s doctor [--json] [runner-type(s)]\tDiagnose the deployment for the given or all runner type(s)\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 
-	if len(os.Args) < 2 {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate-config":
+			os.Exit(validateConfig(args[1:]))
+		case "doctor":
+			os.Exit(doctor(args[1:]))
+		case "launch":
+			args = args[1:]
+		}
+	}
+
+	if len(args) == 0 {
 		os.Stderr.WriteString("Missing runner-type argument(s)\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	runnerTypes := os.Args[1:]
+	os.Exit(launch(args))
+}
 
-	launcherConfig, err := config.LoadLauncherConfig(runnerTypes, envconfig.OsLookuper())
+// launch loads the config for the given runner types and runs the launcher.
+// Returns the exit code.
+func launch(runnerTypes []string) int {
+	launcherConfig, err := loadConfig(runnerTypes)
 	if err != nil {
 		logs.Errorf("Failed to load config: %v", err)
-		os.Exit(1)
+		return 1
+	}
+
+	return run(launcherConfig, runnerTypes)
+}
+
+// loadConfig loads and validates the launcher's config for the given runner
+// types, including whether the launcher can run each runner as its configured user.
+func loadConfig(runnerTypes []string) (*config.LauncherConfig, error) {
+	launcherConfig, err := config.LoadLauncherConfig(runnerTypes, envconfig.OsLookuper())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCredentials(launcherConfig); err != nil {
+		return nil, err
+	}
+
+	return launcherConfig, nil
+}
+
+// checkCredentials checks whether the launcher can run each runner as its
+// configured user.
+func checkCredentials(launcherConfig *config.LauncherConfig) error {
+	var credErrs []error
+	for _, runnerType := range slices.Sorted(maps.Keys(launcherConfig.RunnerConfigs)) {
+		runnerConfig := launcherConfig.RunnerConfigs[runnerType]
+		if runnerConfig.Credential == nil {
+			continue
+		}
+		if err := process.CheckCredential(runnerConfig.Credential); err != nil {
+			credErrs = append(credErrs, fmt.Errorf("cannot run runner %s as configured user: %w", runnerType, err))
+		}
 	}
 
+	return errors.Join(credErrs...)
+}
+
+// run launches and manages runners of the given types until all launch cycles
+// have stopped, either on failure or on SIGTERM/SIGINT. Returns the exit code.
+func run(launcherConfig *config.LauncherConfig, runnerTypes []string) int {
 	errorreporting.Init(launcherConfig.BaseConfig.Sentry)
 	defer errorreporting.Close()
 
-	http.InitHealthCheckServer(launcherConfig.BaseConfig.HealthCheckServerPort)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal will terminate the launcher immediately
+		logs.Info("Received shutdown signal, stopping launcher...")
+	}()
+
+	if err := process.EnableSubreaper(); err != nil {
+		logs.Warnf("Failed to register launcher as subreaper, orphaned runner descendants may be left running: %v", err)
+	}
+
+	initCgroups(launcherConfig)
+
+	registry := status.NewRegistry()
+	supervisor := commands.NewSupervisor(ctx, launcherConfig, registry)
+	supervisor.Start(runnerTypes)
+
+	healthCheckServer := http.InitHealthCheckServer(
+		launcherConfig.BaseConfig.HealthCheckServerPort,
+		registry,
+		launcherConfig.BaseConfig.AdminToken,
+		supervisor,
+	)
+
+	go reloadConfig(ctx, supervisor, sighup, launcherConfig.BaseConfig.ConfigPath, runnerTypes)
+
+	hasFailed := supervisor.Wait()
 
-	var wg sync.WaitGroup
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), healthCheckServerShutdownTimeout)
+	defer cancel()
 
-	for _, runnerType := range runnerTypes {
-		wg.Add(1)
-		go func(rt string) {
-			defer wg.Done()
+	if err := healthCheckServer.Shutdown(shutdownCtx); err != nil {
+		logs.Errorf("Failed to shut down health check server: %v", err)
+	}
+
+	if hasFailed {
+		return 1
+	}
+
+	logs.Info("Launcher stopped")
+
+	return 0
+}
 
-			logLevel := logs.ParseLevel(launcherConfig.BaseConfig.LogLevel)
-			logPrefix := logs.GetLauncherPrefix(runnerType)
-			logger := logs.NewLogger(logLevel, logPrefix)
+// reloadConfig reloads the config on SIGHUP or whenever the config file changes,
+// until the context is cancelled. A config that fails validation is rejected,
+// keeping the current config.
+func reloadConfig(
+	ctx context.Context,
+	supervisor *commands.Supervisor,
+	sighup <-chan os.Signal,
+	configPath string,
+	runnerTypes []string,
+) {
+	changes := config.WatchFile(ctx, configPath, configPollInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			logs.Info("Received SIGHUP, reloading config...")
+		case <-changes:
+			logs.Info("Config file changed, reloading config...")
+		}
+
+		launcherConfig, err := loadConfig(runnerTypes)
+		if err != nil {
+			logs.Errorf("Rejected reloaded config, keeping current config: %v", err)
+			continue
+		}
+
+		initCgroups(launcherConfig)
+		supervisor.Reload(launcherConfig)
+	}
+}
 
-			cmd := commands.NewLaunchCommand(logger)
-			if err := cmd.Execute(launcherConfig, rt); err != nil {
-				logger.Errorf("Failed to execute `launch` command: %v", err)
-			}
-		}(runnerType)
+// initCgroups sets up cgroups for runners, once any runner is configured with
+// cgroup limits.
+func initCgroups(launcherConfig *config.LauncherConfig) {
+	if !launcherConfig.HasCgroupLimits() {
+		return
 	}
 
-	wg.Wait()
+	initCgroupsOnce.Do(func() {
+		if err := cgroup.Init(); err != nil {
+			logs.Warnf("Running in degraded mode, cgroup limits will not be applied to runners: %v", err)
+		}
+	})
 }
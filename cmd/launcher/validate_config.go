@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"task-runner-launcher/internal/config"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// validateConfig validates the launcher's config for the given runner types, or
+// for all runner types in the config file if none are given, without contacting
+// the task broker. Prints all validation errors at once. Returns the exit code.
+func validateConfig(runnerTypes []string) int {
+	launcherConfig, err := config.ValidateLauncherConfig(runnerTypes, envconfig.OsLookuper())
+	if err == nil {
+		err = checkCredentials(launcherConfig)
+	}
+
+	if err != nil {
+		lines := strings.Split(err.Error(), "\n")
+		fmt.Fprintf(os.Stderr, "Config is invalid, found %d error(s):\n", len(lines))
+		for _, line := range lines {
+			fmt.Fprintf(os.Stderr, "  - %s\n", line)
+		}
+		return 1
+	}
+
+	validated := slices.Sorted(maps.Keys(launcherConfig.RunnerConfigs))
+	fmt.Printf("Config is valid for runner type(s): %s\n", strings.Join(validated, ", "))
+
+	return 0
+}
@@ -7,13 +7,14 @@ require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/sethvargo/go-envconfig v1.1.0
 	github.com/stretchr/testify v1.8.4
+	golang.org/x/net v0.22.0
+	golang.org/x/sys v0.18.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"task-runner-launcher/internal/config"
+)
+
+// Endpoint is a task broker that the launcher connects to via its transport.
+type Endpoint struct {
+	URI       string
+	Transport *Transport
+
+	// RunnerURI is the URI that runners connect to the task broker at, i.e. the
+	// URI itself or, for a `unix://` URI, the URI of a relay to the socket.
+	RunnerURI string
+
+	// weight among weighted task brokers, or 0 if ordered by priority
+	weight int
+}
+
+// Pool holds the task brokers that the launcher fails over between.
+type Pool struct {
+	endpoints []*Endpoint
+	relays    []*Relay
+}
+
+// NewPool creates a pool of the task brokers of the given config, each with its
+// own transport, starting a relay for runners to each task broker on a unix
+// socket. The pool is to be closed by the caller.
+func NewPool(baseConfig *config.BaseConfig) (*Pool, error) {
+	pool := &Pool{}
+	for _, b := range baseConfig.TaskBrokers() {
+		transport, err := NewTransport(baseConfig, b.URI)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+
+		endpoint := &Endpoint{URI: b.URI, Transport: transport, RunnerURI: b.URI, weight: b.Weight}
+		if socketPath := SocketPath(b.URI); socketPath != "" {
+			relay, err := NewRelay(socketPath)
+			if err != nil {
+				pool.Close()
+				return nil, err
+			}
+			pool.relays = append(pool.relays, relay)
+			endpoint.RunnerURI = relay.URI()
+		}
+		pool.endpoints = append(pool.endpoints, endpoint)
+	}
+
+	return pool, nil
+}
+
+// Close stops the pool's relays, if any.
+func (p *Pool) Close() {
+	for _, relay := range p.relays {
+		relay.Close()
+	}
+}
+
+// Endpoints returns the task brokers in the order to try them in: in order of
+// priority or, if weighted, in a random order in which a task broker is more
+// likely to come first the higher its weight.
+func (p *Pool) Endpoints() []*Endpoint {
+	endpoints := slices.Clone(p.endpoints)
+	if len(endpoints) < 2 || endpoints[0].weight == 0 {
+		return endpoints
+	}
+
+	// weighted random sampling without replacement, by sorting on a random key
+	// of u^(1/weight) per task broker, with u uniform in [0, 1)
+	keys := make(map[*Endpoint]float64, len(endpoints))
+	for _, e := range endpoints {
+		// #nosec G404 -- the order of task brokers is not security sensitive
+		keys[e] = math.Pow(rand.Float64(), 1/float64(e.weight))
+	}
+	slices.SortStableFunc(endpoints, func(a, b *Endpoint) int {
+		switch {
+		case keys[a] > keys[b]:
+			return -1
+		case keys[a] < keys[b]:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return endpoints
+}
@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"task-runner-launcher/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uris(endpoints []*Endpoint) []string {
+	uris := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		uris[i] = endpoint.URI
+	}
+
+	return uris
+}
+
+func TestPoolEndpoints(t *testing.T) {
+	t.Run("single task broker", func(t *testing.T) {
+		pool, err := NewPool(&config.BaseConfig{TaskBrokerURI: "http://127.0.0.1:5679"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"http://127.0.0.1:5679"}, uris(pool.Endpoints()))
+	})
+
+	t.Run("ordered task brokers", func(t *testing.T) {
+		pool, err := NewPool(&config.BaseConfig{
+			TaskBrokerURI: "http://127.0.0.1:5679",
+			Brokers: []config.Broker{
+				{URI: "http://worker-2:5679"},
+				{URI: "http://worker-1:5679"},
+				{URI: "http://worker-3:5679"},
+			},
+		})
+		require.NoError(t, err)
+
+		for range 10 {
+			assert.Equal(t, []string{"http://worker-2:5679", "http://worker-1:5679", "http://worker-3:5679"}, uris(pool.Endpoints()))
+		}
+	})
+
+	t.Run("weighted task brokers", func(t *testing.T) {
+		pool, err := NewPool(&config.BaseConfig{
+			Brokers: []config.Broker{
+				{URI: "http://worker-1:5679", Weight: 1},
+				{URI: "http://worker-2:5679", Weight: 9},
+			},
+		})
+		require.NoError(t, err)
+
+		first := make(map[string]int)
+		for range 1000 {
+			endpoints := uris(pool.Endpoints())
+			assert.ElementsMatch(t, []string{"http://worker-1:5679", "http://worker-2:5679"}, endpoints)
+			first[endpoints[0]]++
+		}
+
+		// expected 100 vs 900, with a wide margin against flakiness
+		assert.Greater(t, first["http://worker-1:5679"], 30)
+		assert.Greater(t, first["http://worker-2:5679"], 750)
+	})
+}
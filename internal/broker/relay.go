@@ -0,0 +1,119 @@
+package broker
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Relay forwards connections on a loopback TCP port to a task broker's unix
+// socket, as runners connect to the task broker over TCP only. Runners share
+// the launcher's network namespace, also within a sandbox, so they can reach
+// the relay wherever the socket is.
+type Relay struct {
+	listener   net.Listener
+	socketPath string
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewRelay starts a relay to the unix socket at the given path, listening on a
+// free loopback port. The relay is to be closed by the caller.
+func NewRelay(socketPath string) (*Relay, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for relay to %s: %w", socketPath, err)
+	}
+
+	r := &Relay{listener: listener, socketPath: socketPath, conns: make(map[net.Conn]struct{})}
+	go r.serve()
+
+	return r, nil
+}
+
+// URI returns the `http://` URI at which the relay reaches the task broker.
+func (r *Relay) URI() string {
+	return "http://" + r.listener.Addr().String()
+}
+
+// Close stops accepting connections and closes any relayed connections.
+func (r *Relay) Close() error {
+	err := r.listener.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.conns = nil
+
+	return err
+}
+
+func (r *Relay) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return // closed
+		}
+		go r.forward(conn)
+	}
+}
+
+// forward copies data both ways between a relayed connection and a new
+// connection to the socket, until either side closes.
+func (r *Relay) forward(conn net.Conn) {
+	upstream, err := net.Dial("unix", r.socketPath)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	if !r.track(conn, upstream) {
+		return
+	}
+	defer r.untrack(conn, upstream)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// track registers the connections to close on closing the relay, or closes
+// them right away if the relay is already closed.
+func (r *Relay) track(conns ...net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conns == nil {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return false
+	}
+
+	for _, conn := range conns {
+		r.conns[conn] = struct{}{}
+	}
+
+	return true
+}
+
+func (r *Relay) untrack(conns ...net.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+		delete(r.conns, conn)
+	}
+}
@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"task-runner-launcher/internal/config"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSocketServer starts a server listening on a unix socket and returns its
+// `unix://` URI.
+func newSocketServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	// keep the socket path short, as its max length is ~100 bytes
+	dir, err := os.MkdirTemp("", "broker")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "broker.sock")
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return "unix://" + socketPath
+}
+
+func TestRelay(t *testing.T) {
+	uri := newSocketServer(t, websocketHandler())
+
+	relay, err := NewRelay(SocketPath(uri))
+	require.NoError(t, err)
+	defer relay.Close()
+
+	assert.True(t, strings.HasPrefix(relay.URI(), "http://127.0.0.1:"), "Expected relay on loopback, got %s", relay.URI())
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Get(relay.URI() + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(relay.URI(), "http")+"/runners/_ws", nil)
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, relay.Close())
+	_, err = (&http.Client{Timeout: 5 * time.Second}).Get(relay.URI() + "/healthz")
+	assert.Error(t, err, "Expected closed relay to refuse connections")
+}
+
+func TestPoolRelaysUnixSocket(t *testing.T) {
+	uri := newSocketServer(t, websocketHandler())
+
+	pool, err := NewPool(&config.BaseConfig{
+		Brokers: []config.Broker{{URI: "http://worker-1:5679"}, {URI: uri}},
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	endpoints := pool.Endpoints()
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "http://worker-1:5679", endpoints[0].RunnerURI)
+	assert.Equal(t, uri, endpoints[1].URI)
+	assert.True(t, strings.HasPrefix(endpoints[1].RunnerURI, "http://127.0.0.1:"), "Expected runners to get a loopback URI, got %s", endpoints[1].RunnerURI)
+
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Get(endpoints[1].RunnerURI + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,108 @@
+package broker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"task-runner-launcher/internal/config"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport holds how the launcher connects to the task broker, shared by the
+// readiness check, the grant token exchange and the websocket handshake. A nil
+// *Transport connects with Go's defaults.
+type Transport struct {
+	tlsConfig *tls.Config
+	proxy     func(*url.URL) (*url.URL, error)
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	http      *http.Transport
+}
+
+// SocketPath returns the socket path of a `unix://` task broker URI, or an
+// empty string for any other task broker URI.
+func SocketPath(taskBrokerURI string) string {
+	u, err := url.Parse(taskBrokerURI)
+	if err != nil || u.Scheme != "unix" {
+		return ""
+	}
+
+	return u.Path
+}
+
+// HTTPURI returns the URI to send requests to the task broker at, i.e. the
+// task broker URI itself or, for a `unix://` task broker URI, a placeholder
+// `http://` URI, as the transport dials the socket whatever the host.
+func HTTPURI(taskBrokerURI string) string {
+	if SocketPath(taskBrokerURI) != "" {
+		return "http://localhost"
+	}
+
+	return taskBrokerURI
+}
+
+// NewTransport creates a transport connecting to the task broker at the given
+// URI with the TLS and proxy settings of the given config. For a `unix://` task
+// broker URI, every connection dials the socket and no proxy applies.
+func NewTransport(baseConfig *config.BaseConfig, taskBrokerURI string) (*Transport, error) {
+	tlsConfig, err := baseConfig.TLS.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare TLS config: %w", err)
+	}
+
+	proxy := baseConfig.Proxy.ProxyFunc()
+
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	if socketPath := SocketPath(taskBrokerURI); socketPath != "" {
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		}
+		proxy = nil
+	}
+
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.TLSClientConfig = tlsConfig
+	httpTransport.Proxy = nil
+	if proxy != nil {
+		httpTransport.Proxy = func(r *http.Request) (*url.URL, error) {
+			return proxy(r.URL)
+		}
+	}
+	if dial != nil {
+		httpTransport.DialContext = dial
+	}
+
+	return &Transport{tlsConfig: tlsConfig, proxy: proxy, dial: dial, http: httpTransport}, nil
+}
+
+// HTTPClient returns a client for requests to the task broker, with the given
+// timeout, or no timeout if zero.
+func (t *Transport) HTTPClient(timeout time.Duration) *http.Client {
+	if t == nil {
+		return &http.Client{Timeout: timeout}
+	}
+
+	return &http.Client{Transport: t.http, Timeout: timeout}
+}
+
+// Dialer returns a dialer for websocket connections to the task broker, which
+// tunnels through any proxy via CONNECT.
+func (t *Transport) Dialer() *websocket.Dialer {
+	if t == nil {
+		return &websocket.Dialer{}
+	}
+
+	dialer := &websocket.Dialer{TLSClientConfig: t.tlsConfig, NetDialContext: t.dial}
+	if t.proxy != nil {
+		dialer.Proxy = func(r *http.Request) (*url.URL, error) {
+			return t.proxy(r.URL)
+		}
+	}
+
+	return dialer
+}
@@ -0,0 +1,354 @@
+package broker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"slices"
+	"strings"
+	"sync"
+	"task-runner-launcher/internal/config"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clientCert returns a PEM-encoded self-signed client cert, its key, and the
+// parsed cert for the server to trust.
+func clientCert(t *testing.T) (certPEM, keyPEM string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+
+	return certPEM, keyPEM, cert
+}
+
+// newMTLSServer starts a TLS server that requires the given client cert and
+// accepts websocket connections on any path.
+func newMTLSServer(t *testing.T, trusted *x509.Certificate) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(websocketHandler())
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(trusted)
+	srv.TLS = &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestTransportTLS(t *testing.T) {
+	certPEM, keyPEM, cert := clientCert(t)
+	srv := newMTLSServer(t, cert)
+	caCertPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	tests := []struct {
+		name        string
+		tlsConfig   *config.TLSConfig
+		expectError bool
+	}{
+		{
+			name:        "default TLS config",
+			tlsConfig:   nil,
+			expectError: true,
+		},
+		{
+			name:        "custom CA without client cert",
+			tlsConfig:   &config.TLSConfig{CACert: caCertPEM},
+			expectError: true,
+		},
+		{
+			name: "custom CA with client cert",
+			tlsConfig: &config.TLSConfig{
+				CACert:     caCertPEM,
+				ClientCert: certPEM,
+				ClientKey:  keyPEM,
+			},
+		},
+		{
+			name: "server name override",
+			tlsConfig: &config.TLSConfig{
+				CACert:     caCertPEM,
+				ClientCert: certPEM,
+				ClientKey:  keyPEM,
+				ServerName: "example.com",
+			},
+		},
+		{
+			name: "server name mismatch",
+			tlsConfig: &config.TLSConfig{
+				CACert:     caCertPEM,
+				ClientCert: certPEM,
+				ClientKey:  keyPEM,
+				ServerName: "broker.internal",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(&config.BaseConfig{TLS: tt.tlsConfig}, srv.URL)
+			require.NoError(t, err)
+
+			t.Run("http", func(t *testing.T) {
+				resp, err := transport.HTTPClient(5 * time.Second).Get(srv.URL)
+				if tt.expectError {
+					assert.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			})
+
+			t.Run("websocket", func(t *testing.T) {
+				wsURL := "wss" + strings.TrimPrefix(srv.URL, "https")
+				conn, _, err := transport.Dialer().Dial(wsURL, nil)
+				if tt.expectError {
+					assert.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				conn.Close()
+			})
+		})
+	}
+}
+
+func TestNilTransport(t *testing.T) {
+	var transport *Transport
+
+	assert.NotNil(t, transport.HTTPClient(time.Second))
+	assert.NotNil(t, transport.Dialer())
+}
+
+// fakeProxy is an HTTP proxy requiring basic auth, forwarding all requests and
+// CONNECT tunnels to a single backend regardless of the requested host.
+type fakeProxy struct {
+	backend string
+
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (p *fakeProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	credentials := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	if r.Header.Get("Proxy-Authorization") != "Basic "+credentials {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+
+	p.mu.Lock()
+	p.hosts = append(p.hosts, r.Method+" "+r.Host)
+	p.mu.Unlock()
+
+	if r.Method != http.MethodConnect {
+		proxy := &httputil.ReverseProxy{Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = p.backend
+		}}
+		proxy.ServeHTTP(w, r)
+		return
+	}
+
+	backendConn, err := net.Dial("tcp", p.backend)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer backendConn.Close()
+
+	clientConn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer clientConn.Close()
+
+	_, _ = clientConn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(backendConn, clientConn); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(clientConn, backendConn); done <- struct{}{} }()
+	<-done
+}
+
+func (p *fakeProxy) requests() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.hosts)
+}
+
+// websocketHandler accepts websocket connections on any path, and responds
+// with 200 to any other request.
+func websocketHandler() http.Handler {
+	upgrader := websocket.Upgrader{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func newWebsocketServer(t *testing.T, useTLS bool) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(websocketHandler())
+	if useTLS {
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestTransportProxy(t *testing.T) {
+	plainSrv := newWebsocketServer(t, false)
+	tlsSrv := newWebsocketServer(t, true)
+	caCertPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw}))
+
+	tests := []struct {
+		name             string
+		backend          *httptest.Server
+		brokerURI        string
+		proxyCredentials string
+		expectedRequests []string
+		expectError      bool
+	}{
+		{
+			name:             "http via proxy",
+			backend:          plainSrv,
+			brokerURI:        "http://broker.test",
+			proxyCredentials: "user:pass@",
+			// the websocket connection is tunnelled even over http
+			expectedRequests: []string{"GET broker.test", "CONNECT broker.test:80"},
+		},
+		{
+			name:             "https tunnelled via proxy",
+			backend:          tlsSrv,
+			brokerURI:        "https://broker.test",
+			proxyCredentials: "user:pass@",
+			expectedRequests: []string{"CONNECT broker.test:443", "CONNECT broker.test:443"},
+		},
+		{
+			name:             "missing proxy credentials",
+			backend:          plainSrv,
+			brokerURI:        "http://broker.test",
+			expectedRequests: []string{},
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &fakeProxy{backend: tt.backend.Listener.Addr().String()}
+			proxySrv := httptest.NewServer(proxy)
+			defer proxySrv.Close()
+
+			proxyURL := "http://" + tt.proxyCredentials + strings.TrimPrefix(proxySrv.URL, "http://")
+			transport, err := NewTransport(&config.BaseConfig{
+				TLS: &config.TLSConfig{CACert: caCertPEM, ServerName: "example.com"},
+				Proxy: &config.ProxyConfig{
+					HTTPProxy:  proxyURL,
+					HTTPSProxy: proxyURL,
+				},
+			}, tt.brokerURI)
+			require.NoError(t, err)
+
+			resp, err := transport.HTTPClient(5 * time.Second).Get(tt.brokerURI + "/healthz")
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(tt.brokerURI, "http") + "/runners/_ws"
+			conn, _, wsErr := transport.Dialer().Dial(wsURL, nil)
+			if wsErr == nil {
+				conn.Close()
+			}
+
+			if tt.expectError {
+				assert.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+				assert.Error(t, wsErr)
+			} else {
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+				assert.NoError(t, wsErr)
+			}
+			assert.ElementsMatch(t, tt.expectedRequests, proxy.requests())
+		})
+	}
+}
+
+func TestTransportUnixSocket(t *testing.T) {
+	uri := newSocketServer(t, websocketHandler())
+	assert.Equal(t, strings.TrimPrefix(uri, "unix://"), SocketPath(uri))
+	assert.Equal(t, "http://localhost", HTTPURI(uri))
+
+	transport, err := NewTransport(&config.BaseConfig{
+		// never applies to a unix socket
+		Proxy: &config.ProxyConfig{HTTPProxy: "http://proxy.invalid:3128"},
+	}, uri)
+	require.NoError(t, err)
+
+	resp, err := transport.HTTPClient(5 * time.Second).Get(HTTPURI(uri) + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	conn, _, err := transport.Dialer().Dial("ws://localhost/runners/_ws", nil)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestHTTPURI(t *testing.T) {
+	assert.Equal(t, "https://broker.internal:5679", HTTPURI("https://broker.internal:5679"))
+	assert.Empty(t, SocketPath("https://broker.internal:5679"))
+}
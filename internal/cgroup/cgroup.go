@@ -0,0 +1,170 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/logs"
+	"time"
+)
+
+const (
+	// cpuPeriod is the period (in microseconds) over which `cpu-quota` applies.
+	cpuPeriod = 100000
+
+	// removeAttempts is the max number of attempts to remove a cgroup, which
+	// fails while the kernel is still releasing the cgroup's exited processes.
+	removeAttempts = 10
+
+	removeInterval = 50 * time.Millisecond
+)
+
+// ErrUnavailable is returned when the launcher cannot manage cgroups, e.g. when
+// cgroup v2 is not mounted or the launcher's cgroup is not writable.
+var ErrUnavailable = errors.New("cgroup v2 is unavailable")
+
+var (
+	// root is the launcher's own cgroup, under which a child cgroup is created per
+	// runner launch. Empty if cgroups are unavailable.
+	root string
+
+	// enabledControllers holds the controllers enabled for child cgroups of root.
+	enabledControllers = make(map[string]bool)
+)
+
+// Cgroup is a child cgroup created for a single runner launch.
+type Cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// limit is a value to write into a cgroup interface file.
+type limit struct {
+	file  string
+	value string
+}
+
+// controller returns the controller the interface file belongs to, e.g. `memory`
+// for `memory.max`.
+func (l limit) controller() string {
+	return strings.SplitN(l.file, ".", 2)[0]
+}
+
+// limits converts a runner's cgroup config into the values to write into the
+// cgroup's interface files.
+func limits(cfg *config.CgroupConfig) []limit {
+	var result []limit
+
+	for _, l := range []limit{{"memory.max", cfg.MemoryMax}, {"memory.high", cfg.MemoryHigh}} {
+		if l.value == "" {
+			continue
+		}
+		value := "max"
+		if bytes, err := config.ParseByteSize(l.value); err == nil && bytes > 0 {
+			value = strconv.FormatInt(bytes, 10)
+		}
+		result = append(result, limit{l.file, value})
+	}
+
+	if cfg.CPUWeight > 0 {
+		result = append(result, limit{"cpu.weight", strconv.Itoa(cfg.CPUWeight)})
+	}
+
+	if cfg.CPUQuota > 0 {
+		quota := max(1000, int(cfg.CPUQuota*cpuPeriod)) // kernel min is 1ms
+		result = append(result, limit{"cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)})
+	}
+
+	if cfg.PidsMax > 0 {
+		result = append(result, limit{"pids.max", strconv.Itoa(cfg.PidsMax)})
+	}
+
+	return result
+}
+
+// Create creates a child cgroup of the launcher's cgroup with the given name
+// prefix and applies the given limits to it. Limits for controllers that are not
+// available to the launcher are skipped with a warning.
+func Create(namePrefix string, cfg *config.CgroupConfig) (*Cgroup, error) {
+	if root == "" {
+		return nil, ErrUnavailable
+	}
+
+	path, err := os.MkdirTemp(root, namePrefix+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	cg := &Cgroup{path: path}
+
+	for _, l := range limits(cfg) {
+		if !enabledControllers[l.controller()] {
+			logs.Warnf("Skipped cgroup limit %s, as the %s controller is not available to the launcher", l.file, l.controller())
+			continue
+		}
+
+		if err := os.WriteFile(filepath.Join(path, l.file), []byte(l.value), 0); err != nil {
+			_ = cg.Remove()
+			return nil, fmt.Errorf("failed to set cgroup limit %s to %s: %w", l.file, l.value, err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		_ = cg.Remove()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	cg.dir = dir
+
+	return cg, nil
+}
+
+// Path returns the path of the cgroup in the cgroup filesystem.
+func (c *Cgroup) Path() string {
+	return c.path
+}
+
+// OOMKilled reports whether the kernel OOM killer has killed any process in the
+// cgroup.
+func (c *Cgroup) OOMKilled() bool {
+	f, err := os.Open(filepath.Join(c.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, _ := strconv.Atoi(fields[1])
+			return count > 0
+		}
+	}
+
+	return false
+}
+
+// Remove kills any process left in the cgroup and removes the cgroup.
+func (c *Cgroup) Remove() error {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+
+	_ = os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0)
+
+	var err error
+	for range removeAttempts {
+		if err = os.RemoveAll(c.path); err == nil {
+			return nil
+		}
+		time.Sleep(removeInterval)
+	}
+
+	return fmt.Errorf("failed to remove cgroup %s: %w", c.path, err)
+}
@@ -0,0 +1,167 @@
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"task-runner-launcher/internal/logs"
+)
+
+const (
+	// launcherCgroup is the leaf cgroup that the launcher moves the processes of
+	// its own cgroup into, since a cgroup with processes cannot delegate
+	// controllers to child cgroups.
+	launcherCgroup = "launcher"
+
+	// accessWriteOK is the `access` mode to check for write permission.
+	accessWriteOK = 0x2
+)
+
+// controllers are the controllers the launcher enables for runner cgroups.
+var controllers = []string{"memory", "cpu", "pids"}
+
+// findCgroup2Mount returns the mount point of the cgroup v2 filesystem listed
+// in the given `/proc/self/mountinfo` content.
+func findCgroup2Mount(mountinfo io.Reader) (string, bool) {
+	scanner := bufio.NewScanner(mountinfo)
+	for scanner.Scan() {
+		// optional fields precede the ` - ` separator, followed by the fs type
+		before, after, found := strings.Cut(scanner.Text(), " - ")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(before)
+		if len(fields) >= 5 && strings.HasPrefix(after, "cgroup2 ") {
+			return fields[4], true
+		}
+	}
+
+	return "", false
+}
+
+// findOwnCgroup returns the path of the cgroup v2 hierarchy entry listed in the
+// given `/proc/self/cgroup` content.
+func findOwnCgroup(procCgroup io.Reader) (string, bool) {
+	scanner := bufio.NewScanner(procCgroup)
+	for scanner.Scan() {
+		if path, found := strings.CutPrefix(scanner.Text(), "0::"); found {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// Init prepares the launcher's own cgroup for creating a child cgroup per
+// runner launch, by enabling the memory, cpu and pids controllers for child
+// cgroups, first moving the cgroup's processes into a leaf cgroup if needed.
+// Returns an error wrapping `ErrUnavailable` if cgroup v2 is not available to
+// the launcher.
+func Init() error {
+	mountinfo, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer mountinfo.Close()
+
+	mount, found := findCgroup2Mount(mountinfo)
+	if !found {
+		return fmt.Errorf("%w: no cgroup2 filesystem is mounted", ErrUnavailable)
+	}
+
+	procCgroup, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer procCgroup.Close()
+
+	ownPath, found := findOwnCgroup(procCgroup)
+	if !found {
+		return fmt.Errorf("%w: launcher is not in a cgroup v2 hierarchy", ErrUnavailable)
+	}
+
+	base := filepath.Join(mount, ownPath)
+	if err := syscall.Access(base, accessWriteOK); err != nil {
+		return fmt.Errorf("%w: launcher's cgroup %s is not writable", ErrUnavailable, base)
+	}
+
+	// #nosec G304 -- path is within the cgroup filesystem
+	available, err := os.ReadFile(filepath.Join(base, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	hasMovedProcesses := false
+	for _, controller := range controllers {
+		if !slices.Contains(strings.Fields(string(available)), controller) {
+			continue
+		}
+
+		err := enableController(base, controller)
+		if errors.Is(err, syscall.EBUSY) && !hasMovedProcesses {
+			if err := moveProcesses(base, filepath.Join(base, launcherCgroup)); err != nil {
+				return fmt.Errorf("%w: %v", ErrUnavailable, err)
+			}
+			hasMovedProcesses = true
+			err = enableController(base, controller)
+		}
+		if err != nil {
+			logs.Warnf("Failed to enable cgroup %s controller: %v", controller, err)
+			continue
+		}
+
+		enabledControllers[controller] = true
+	}
+
+	if len(enabledControllers) == 0 {
+		return fmt.Errorf("%w: none of the %s controllers are available", ErrUnavailable, strings.Join(controllers, ", "))
+	}
+
+	root = base
+
+	logs.Debugf("Runner cgroups will be created under %s", root)
+
+	return nil
+}
+
+// enableController enables the given controller for the child cgroups of the
+// given cgroup. Fails with EBUSY if the cgroup is not the root cgroup and has
+// processes of its own.
+func enableController(cgroup, controller string) error {
+	return os.WriteFile(filepath.Join(cgroup, "cgroup.subtree_control"), []byte("+"+controller), 0)
+}
+
+// moveProcesses moves every process in the source cgroup into the target leaf
+// cgroup, creating the target if needed.
+func moveProcesses(source, target string) error {
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %w", target, err)
+	}
+
+	// #nosec G304 -- path is within the cgroup filesystem
+	procs, err := os.ReadFile(filepath.Join(source, "cgroup.procs"))
+	if err != nil {
+		return fmt.Errorf("failed to list processes in cgroup %s: %w", source, err)
+	}
+
+	for _, pid := range strings.Fields(string(procs)) {
+		if err := os.WriteFile(filepath.Join(target, "cgroup.procs"), []byte(pid), 0); err != nil {
+			return fmt.Errorf("failed to move process %s into cgroup %s: %w", pid, target, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply sets up the process attributes so that the process is started directly
+// inside the cgroup, before it runs any code.
+func (c *Cgroup) Apply(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(c.dir.Fd())
+}
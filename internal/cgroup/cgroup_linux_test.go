@@ -0,0 +1,82 @@
+package cgroup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCgroup2Mount(t *testing.T) {
+	tests := []struct {
+		name          string
+		mountinfo     string
+		expectedMount string
+		expectedFound bool
+	}{
+		{
+			name: "unified hierarchy",
+			mountinfo: "22 1 0:21 / / rw,relatime - overlay overlay rw\n" +
+				"30 22 0:26 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate\n",
+			expectedMount: "/sys/fs/cgroup",
+			expectedFound: true,
+		},
+		{
+			name: "hybrid hierarchy",
+			mountinfo: "31 30 0:27 / /sys/fs/cgroup/memory rw,nosuid - cgroup cgroup rw,memory\n" +
+				"32 30 0:28 / /sys/fs/cgroup/unified rw,nosuid - cgroup2 cgroup2 rw\n",
+			expectedMount: "/sys/fs/cgroup/unified",
+			expectedFound: true,
+		},
+		{
+			name:          "cgroup v1 only",
+			mountinfo:     "31 30 0:27 / /sys/fs/cgroup/memory rw,nosuid - cgroup cgroup rw,memory\n",
+			expectedMount: "",
+			expectedFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mount, found := findCgroup2Mount(strings.NewReader(tt.mountinfo))
+			assert.Equal(t, tt.expectedMount, mount)
+			assert.Equal(t, tt.expectedFound, found)
+		})
+	}
+}
+
+func TestFindOwnCgroup(t *testing.T) {
+	tests := []struct {
+		name          string
+		procCgroup    string
+		expectedPath  string
+		expectedFound bool
+	}{
+		{
+			name:          "unified hierarchy",
+			procCgroup:    "0::/kubepods/pod123/abc\n",
+			expectedPath:  "/kubepods/pod123/abc",
+			expectedFound: true,
+		},
+		{
+			name:          "hybrid hierarchy",
+			procCgroup:    "4:memory:/docker/abc\n0::/docker/abc\n",
+			expectedPath:  "/docker/abc",
+			expectedFound: true,
+		},
+		{
+			name:          "cgroup v1 only",
+			procCgroup:    "4:memory:/docker/abc\n",
+			expectedPath:  "",
+			expectedFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, found := findOwnCgroup(strings.NewReader(tt.procCgroup))
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, tt.expectedFound, found)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package cgroup
+
+import "syscall"
+
+// Init always fails outside Linux, where cgroups do not exist.
+func Init() error {
+	return ErrUnavailable
+}
+
+// Apply is a no-op outside Linux.
+func (c *Cgroup) Apply(_ *syscall.SysProcAttr) {}
@@ -0,0 +1,145 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"task-runner-launcher/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFakeRoot points the package at a temp dir standing in for the launcher's
+// cgroup, with the given controllers enabled.
+func useFakeRoot(t *testing.T, controllers ...string) string {
+	t.Helper()
+
+	origRoot, origControllers := root, enabledControllers
+	t.Cleanup(func() {
+		root, enabledControllers = origRoot, origControllers
+	})
+
+	root = t.TempDir()
+	enabledControllers = make(map[string]bool)
+	for _, controller := range controllers {
+		enabledControllers[controller] = true
+	}
+
+	return root
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.CgroupConfig
+		expected []limit
+	}{
+		{
+			name:     "no limits",
+			cfg:      &config.CgroupConfig{},
+			expected: nil,
+		},
+		{
+			name: "all limits",
+			cfg: &config.CgroupConfig{
+				MemoryMax:  "512M",
+				MemoryHigh: "384M",
+				CPUWeight:  50,
+				CPUQuota:   0.5,
+				PidsMax:    64,
+			},
+			expected: []limit{
+				{"memory.max", "536870912"},
+				{"memory.high", "402653184"},
+				{"cpu.weight", "50"},
+				{"cpu.max", "50000 100000"},
+				{"pids.max", "64"},
+			},
+		},
+		{
+			name:     "unlimited memory",
+			cfg:      &config.CgroupConfig{MemoryMax: "max"},
+			expected: []limit{{"memory.max", "max"}},
+		},
+		{
+			name:     "tiny cpu quota is raised to kernel min",
+			cfg:      &config.CgroupConfig{CPUQuota: 0.001},
+			expected: []limit{{"cpu.max", "1000 100000"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, limits(tt.cfg))
+		})
+	}
+}
+
+func TestCreateUnavailable(t *testing.T) {
+	origRoot := root
+	root = ""
+	defer func() { root = origRoot }()
+
+	_, err := Create("runner-javascript", &config.CgroupConfig{PidsMax: 10})
+
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	fakeRoot := useFakeRoot(t, "memory", "pids")
+
+	cg, err := Create("runner-javascript", &config.CgroupConfig{MemoryMax: "1G", CPUWeight: 200, PidsMax: 10})
+	require.NoError(t, err)
+
+	assert.Equal(t, fakeRoot, filepath.Dir(cg.Path()))
+	assert.Contains(t, filepath.Base(cg.Path()), "runner-javascript-")
+
+	memoryMax, err := os.ReadFile(filepath.Join(cg.Path(), "memory.max"))
+	require.NoError(t, err)
+	assert.Equal(t, "1073741824", string(memoryMax))
+
+	pidsMax, err := os.ReadFile(filepath.Join(cg.Path(), "pids.max"))
+	require.NoError(t, err)
+	assert.Equal(t, "10", string(pidsMax))
+
+	assert.NoFileExists(t, filepath.Join(cg.Path(), "cpu.weight"), "limit for unavailable controller should be skipped")
+
+	require.NoError(t, cg.Remove())
+	assert.NoDirExists(t, cg.Path())
+}
+
+func TestOOMKilled(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   string
+		expected bool
+	}{
+		{
+			name:     "no memory events",
+			events:   "",
+			expected: false,
+		},
+		{
+			name:     "no OOM kills",
+			events:   "low 0\nhigh 3\nmax 1\noom 1\noom_kill 0\n",
+			expected: false,
+		},
+		{
+			name:     "OOM kill",
+			events:   "low 0\nhigh 3\nmax 5\noom 1\noom_kill 1\n",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := &Cgroup{path: t.TempDir()}
+			if tt.events != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(cg.path, "memory.events"), []byte(tt.events), 0o600))
+			}
+
+			assert.Equal(t, tt.expected, cg.OOMKilled())
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
+	"time"
+)
+
+const (
+	// crashBackoffBase is the delay before relaunching after the first crash,
+	// doubling with each further consecutive crash.
+	crashBackoffBase = 1 * time.Second
+
+	// crashBackoffMax is the max delay before relaunching after a crash.
+	crashBackoffMax = 30 * time.Second
+)
+
+// crashLoop tracks consecutive failed launches of a runner type, shared by all
+// instances of the runner type. After each failed launch, it delays the next
+// launch with exponential backoff and jitter. After too many consecutive failed
+// launches, it opens the runner type's circuit, delaying the next launch until
+// a cool-down has passed.
+type crashLoop struct {
+	runnerType string
+	threshold  int
+	cooldown   time.Duration
+	registry   *status.Registry
+	logger     *logs.Logger
+
+	mu          sync.Mutex
+	failures    int
+	nextAttempt time.Time
+}
+
+func newCrashLoop(runnerType string, threshold int, cooldown time.Duration, registry *status.Registry, logger *logs.Logger) *crashLoop {
+	registry.Update(runnerType, closeCircuit)
+
+	return &crashLoop{
+		runnerType: runnerType,
+		threshold:  threshold,
+		cooldown:   cooldown,
+		registry:   registry,
+		logger:     logger,
+	}
+}
+
+// record records whether a launch of the runner type failed.
+func (l *crashLoop) record(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !failed {
+		if l.failures >= l.threshold {
+			l.logger.Info("Runner launched successfully, closing circuit")
+		}
+		l.failures = 0
+		l.nextAttempt = time.Time{}
+		l.registry.Update(l.runnerType, closeCircuit)
+		return
+	}
+
+	l.failures++
+
+	if l.failures >= l.threshold {
+		openUntil := time.Now().Add(l.cooldown)
+		l.nextAttempt = openUntil
+		l.logger.Errorf("Runner failed to launch %d times in a row, opening circuit: no tasks will be offered for %v", l.failures, l.cooldown)
+		l.registry.Update(l.runnerType, func(runner *status.Runner) {
+			runner.Circuit = status.CircuitOpen
+			runner.ConsecutiveFailures = l.failures
+			runner.CircuitOpenUntil = &openUntil
+		})
+		return
+	}
+
+	delay := backoff(l.failures)
+	l.nextAttempt = time.Now().Add(delay)
+	l.logger.Warnf("Runner failed to launch (%d/%d), relaunching in %v", l.failures, l.threshold, delay.Round(time.Millisecond))
+	l.registry.Update(l.runnerType, func(runner *status.Runner) {
+		runner.ConsecutiveFailures = l.failures
+	})
+}
+
+// isBackingOff returns whether the next launch of the runner type is delayed.
+func (l *crashLoop) isBackingOff() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.nextAttempt) > 0
+}
+
+// wait blocks until the runner type may be launched again, or until the context
+// is cancelled, in which case it returns false.
+func (l *crashLoop) wait(ctx context.Context) bool {
+	for {
+		l.mu.Lock()
+		delay := time.Until(l.nextAttempt)
+		isOpen := l.failures >= l.threshold
+		l.mu.Unlock()
+
+		if delay <= 0 {
+			if isOpen {
+				l.registry.Update(l.runnerType, func(runner *status.Runner) {
+					if runner.Circuit == status.CircuitOpen {
+						l.logger.Info("Circuit cool-down has passed, retrying launch...")
+						runner.Circuit = status.CircuitHalfOpen
+						runner.CircuitOpenUntil = nil
+					}
+				})
+			}
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// closeCircuit resets the circuit of a runner type's status, keeping the
+// runner type's last exit.
+func closeCircuit(runner *status.Runner) {
+	runner.Circuit = status.CircuitClosed
+	runner.ConsecutiveFailures = 0
+	runner.CircuitOpenUntil = nil
+}
+
+// backoff returns the delay before relaunching after the given number of
+// consecutive crashes, i.e. half of the exponential backoff plus a random jitter
+// of up to the other half.
+func backoff(failures int) time.Duration {
+	delay := crashBackoffMax
+	if failures < 32 {
+		delay = min(crashBackoffMax, crashBackoffBase<<(failures-1))
+	}
+
+	return delay/2 + rand.N(delay/2+1) // #nosec G404 -- jitter need not be secure
+}
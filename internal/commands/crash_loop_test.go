@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"context"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{failures: 1, min: 500 * time.Millisecond, max: 1 * time.Second},
+		{failures: 2, min: 1 * time.Second, max: 2 * time.Second},
+		{failures: 4, min: 4 * time.Second, max: 8 * time.Second},
+		{failures: 10, min: crashBackoffMax / 2, max: crashBackoffMax},
+		{failures: 100, min: crashBackoffMax / 2, max: crashBackoffMax},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			delay := backoff(tt.failures)
+			assert.GreaterOrEqual(t, delay, tt.min, "failures: %d", tt.failures)
+			assert.LessOrEqual(t, delay, tt.max, "failures: %d", tt.failures)
+		}
+	}
+}
+
+func TestCrashLoop(t *testing.T) {
+	registry := status.NewRegistry()
+	logger := logs.NewLogger(logs.ErrorLevel, "")
+	crashes := newCrashLoop("javascript", 2, 50*time.Millisecond, registry, logger)
+	ctx := context.Background()
+
+	assert.Equal(t, status.CircuitClosed, registry.Runners()["javascript"].Circuit)
+	assert.True(t, crashes.wait(ctx), "first launch should not wait")
+
+	crashes.record(true)
+	assert.Equal(t, status.CircuitClosed, registry.Runners()["javascript"].Circuit)
+	assert.Equal(t, 1, registry.Runners()["javascript"].ConsecutiveFailures)
+	assert.Greater(t, time.Until(crashes.nextAttempt), time.Duration(0), "should back off after crash")
+
+	crashes.nextAttempt = time.Now() // skip backoff
+	crashes.record(true)
+	runner := registry.Runners()["javascript"]
+	assert.Equal(t, status.CircuitOpen, runner.Circuit)
+	assert.Equal(t, 2, runner.ConsecutiveFailures)
+	assert.NotNil(t, runner.CircuitOpenUntil)
+	assert.True(t, registry.IsDegraded())
+
+	start := time.Now()
+	assert.True(t, crashes.wait(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "should wait for cool-down")
+	assert.Equal(t, status.CircuitHalfOpen, registry.Runners()["javascript"].Circuit)
+
+	registry.Update("javascript", func(runner *status.Runner) {
+		runner.LastExit = &status.RunnerExit{ExitCode: 0}
+	})
+	crashes.record(false)
+	runner = registry.Runners()["javascript"]
+	assert.Equal(t, status.CircuitClosed, runner.Circuit)
+	assert.Equal(t, 0, runner.ConsecutiveFailures)
+	assert.Nil(t, runner.CircuitOpenUntil)
+	assert.NotNil(t, runner.LastExit, "should keep last exit")
+	assert.False(t, registry.IsDegraded())
+}
+
+func TestCrashLoopWaitCancellation(t *testing.T) {
+	registry := status.NewRegistry()
+	logger := logs.NewLogger(logs.ErrorLevel, "")
+	crashes := newCrashLoop("javascript", 1, time.Hour, registry, logger)
+	crashes.record(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.False(t, crashes.wait(ctx))
+}
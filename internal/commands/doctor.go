@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"task-runner-launcher/internal/broker"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/env"
+	"task-runner-launcher/internal/http"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/ws"
+	"time"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+// doctorCheckTimeout is the max time for each check against the task broker.
+const doctorCheckTimeout = 10 * time.Second
+
+// DoctorCheck is the outcome of a single check run by the `doctor` command.
+type DoctorCheck struct {
+	Name       string `json:"name"`
+	RunnerType string `json:"runnerType,omitempty"`
+	Broker     string `json:"broker,omitempty"`
+	Passed     bool   `json:"passed"`
+	Detail     string `json:"detail"`
+
+	// Env holds the names of the env vars the runner would receive, for the
+	// `env` check only. Values are left out as they may hold secrets.
+	Env []string `json:"env,omitempty"`
+}
+
+// DoctorReport is the outcome of all checks run by the `doctor` command.
+type DoctorReport struct {
+	Passed bool          `json:"passed"`
+	Checks []DoctorCheck `json:"checks"`
+}
+
+func (r *DoctorReport) add(check DoctorCheck) {
+	r.Checks = append(r.Checks, check)
+	r.Passed = r.Passed && check.Passed
+}
+
+// DoctorCommand diagnoses a deployment of the launcher: it loads the config and
+// checks each task broker's readiness, auth and websocket endpoints, and for each
+// runner type, whether its health check ports are free, whether its command
+// resolves, and which env vars it would receive. It never launches a runner nor
+// sends a task offer.
+type DoctorCommand struct {
+	logger *logs.Logger
+}
+
+func NewDoctorCommand(logger *logs.Logger) *DoctorCommand {
+	return &DoctorCommand{logger: logger}
+}
+
+// Execute runs all checks for the given runner types, or for all runner types
+// in the config file if none are given, and returns a report of their outcomes.
+func (c *DoctorCommand) Execute(ctx context.Context, runnerTypes []string, lookuper envconfig.Lookuper) DoctorReport {
+	report := DoctorReport{Passed: true}
+
+	launcherConfig, err := config.LoadLauncherConfig(runnerTypes, lookuper)
+	if err != nil {
+		report.add(DoctorCheck{Name: "config", Detail: err.Error()})
+		return report
+	}
+
+	loaded := slices.Sorted(maps.Keys(launcherConfig.RunnerConfigs))
+	report.add(DoctorCheck{
+		Name:   "config",
+		Passed: true,
+		Detail: fmt.Sprintf("loaded config for runner type(s): %s", strings.Join(loaded, ", ")),
+	})
+
+	// task brokers are checked once each, even if shared by runner types
+	type checked struct{ uri, authToken string }
+	seen := make(map[checked]bool)
+
+	for _, runnerType := range loaded {
+		baseConfig := launcherConfig.RunnerBaseConfig(runnerType)
+
+		for _, b := range baseConfig.TaskBrokers() {
+			if seen[checked{b.URI, baseConfig.AuthToken}] {
+				continue
+			}
+			seen[checked{b.URI, baseConfig.AuthToken}] = true
+
+			// validated on load
+			transport, _ := broker.NewTransport(baseConfig, b.URI)
+			endpoint := &broker.Endpoint{URI: b.URI, Transport: transport}
+
+			for _, check := range c.checkBroker(ctx, endpoint, baseConfig.AuthToken) {
+				check.Broker = endpoint.URI
+				report.add(check)
+			}
+		}
+	}
+
+	for _, runnerType := range loaded {
+		baseConfig := launcherConfig.RunnerBaseConfig(runnerType)
+		runnerConfig := launcherConfig.RunnerConfigs[runnerType]
+		report.add(checkHealthCheckPorts(baseConfig.RunnerHealthCheckServerHost, runnerConfig))
+		report.add(checkCommand(runnerConfig))
+		report.add(c.checkEnv(baseConfig, runnerConfig))
+	}
+
+	return report
+}
+
+// checkBroker checks the task broker's readiness, auth and websocket endpoints.
+func (c *DoctorCommand) checkBroker(ctx context.Context, endpoint *broker.Endpoint, authToken string) []DoctorCheck {
+	health := checkBrokerHealth(ctx, endpoint.Transport, endpoint.URI)
+	if !health.Passed {
+		// the exchange would only be retried until timing out
+		return []DoctorCheck{
+			health,
+			{Name: "broker-auth", Detail: "skipped, task broker is not ready"},
+			{Name: "broker-websocket", Detail: "skipped, task broker is not ready"},
+		}
+	}
+
+	grantToken, auth := checkBrokerAuth(ctx, endpoint.Transport, endpoint.URI, authToken)
+	websocket := c.checkBrokerWebsocket(ctx, endpoint.Transport, endpoint.URI, grantToken)
+
+	return []DoctorCheck{health, auth, websocket}
+}
+
+func checkBrokerHealth(ctx context.Context, transport *broker.Transport, taskBrokerURI string) DoctorCheck {
+	check := DoctorCheck{Name: "broker-health"}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	if err := http.CheckBrokerReady(ctx, transport, taskBrokerURI); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = fmt.Sprintf("task broker at %s is ready", taskBrokerURI)
+
+	return check
+}
+
+func checkBrokerAuth(ctx context.Context, transport *broker.Transport, taskBrokerURI, authToken string) (string, DoctorCheck) {
+	check := DoctorCheck{Name: "broker-auth"}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	grantToken, err := http.FetchGrantToken(ctx, transport, taskBrokerURI, authToken)
+	if err != nil {
+		check.Detail = err.Error()
+		return "", check
+	}
+
+	check.Passed = true
+	check.Detail = "exchanged auth token for grant token"
+
+	return grantToken, check
+}
+
+func (c *DoctorCommand) checkBrokerWebsocket(ctx context.Context, transport *broker.Transport, taskBrokerURI, grantToken string) DoctorCheck {
+	check := DoctorCheck{Name: "broker-websocket"}
+
+	if grantToken == "" {
+		check.Detail = "skipped, no grant token to connect with"
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, doctorCheckTimeout)
+	defer cancel()
+
+	if err := ws.Probe(ctx, transport, taskBrokerURI, grantToken, c.logger); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = "websocket endpoint accepted grant token"
+
+	return check
+}
+
+func checkHealthCheckPorts(host string, runnerConfig *config.RunnerConfig) DoctorCheck {
+	check := DoctorCheck{Name: "health-check-ports", RunnerType: runnerConfig.RunnerType}
+
+	ports := runnerConfig.HealthCheckServerPorts()
+
+	var inUse []string
+	for _, port := range ports {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			inUse = append(inUse, port)
+			continue
+		}
+		l.Close()
+	}
+
+	if len(inUse) > 0 {
+		check.Detail = fmt.Sprintf("port(s) in use on %s: %s", host, strings.Join(inUse, ", "))
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = fmt.Sprintf("port(s) free on %s: %s", host, strings.Join(ports, ", "))
+
+	return check
+}
+
+func checkCommand(runnerConfig *config.RunnerConfig) DoctorCheck {
+	check := DoctorCheck{Name: "command", RunnerType: runnerConfig.RunnerType}
+
+	if err := runnerConfig.CheckCommand(); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Passed = true
+	check.Detail = fmt.Sprintf("command %s resolves", runnerConfig.Command)
+
+	return check
+}
+
+func (c *DoctorCommand) checkEnv(baseConfig *config.BaseConfig, runnerConfig *config.RunnerConfig) DoctorCheck {
+	// the grant token and any scratch dir are added on each launch
+	runnerEnv := append(env.PrepareRunnerEnv(baseConfig, runnerConfig, c.logger), env.EnvVarGrantToken+"=")
+	if runnerConfig.ScratchDir {
+		runnerEnv = append(runnerEnv, env.EnvVarScratchDir+"=")
+	}
+
+	names := make([]string, len(runnerEnv))
+	for i, envVar := range runnerEnv {
+		names[i], _, _ = strings.Cut(envVar, "=")
+	}
+	slices.Sort(names)
+
+	return DoctorCheck{
+		Name:       "env",
+		RunnerType: runnerConfig.RunnerType,
+		Passed:     true,
+		Detail:     fmt.Sprintf("runner would receive %d env var(s)", len(names)),
+		Env:        names,
+	}
+}
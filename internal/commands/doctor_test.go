@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"task-runner-launcher/internal/logs"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sethvargo/go-envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeBroker starts a task broker that accepts the given auth token and,
+// over websocket, the grant token it hands out.
+func newFakeBroker(t *testing.T, authToken string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(fakeBrokerHandler(authToken))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// newFakeSocketBroker starts a fake task broker listening on a unix socket, and
+// returns its `unix://` URI.
+func newFakeSocketBroker(t *testing.T, authToken string) string {
+	t.Helper()
+
+	// keep the socket path short, as its max length is ~100 bytes
+	dir, err := os.MkdirTemp("", "broker")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socketPath := filepath.Join(dir, "broker.sock")
+	l, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(fakeBrokerHandler(authToken))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return "unix://" + socketPath
+}
+
+func fakeBrokerHandler(authToken string) http.Handler {
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /runners/auth", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token != authToken {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"token": "grant-token"}}`))
+	})
+	mux.HandleFunc("GET /runners/_ws", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer grant-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteJSON(map[string]string{"type": "broker:inforequest"})
+		_, _, _ = conn.ReadMessage()
+	})
+
+	return mux
+}
+
+func writeDoctorConfig(t *testing.T, dir, command, port string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "config.json")
+	content := `{
+		"task-runners": [{
+			"runner-type": "javascript",
+			"workdir": "` + dir + `",
+			"command": "` + command + `",
+			"allowed-env": ["PATH"],
+			"env-overrides": {"NODE_OPTIONS": "--max-old-space-size=512"},
+			"health-check-server-port": "` + port + `"
+		}]
+	}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+
+	return port
+}
+
+func checksByName(report DoctorReport) map[string]DoctorCheck {
+	checks := make(map[string]DoctorCheck)
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+
+	return checks
+}
+
+func TestDoctor(t *testing.T) {
+	broker := newFakeBroker(t, "auth-token")
+	dir := t.TempDir()
+	runnerPath := filepath.Join(dir, "runner")
+	require.NoError(t, os.WriteFile(runnerPath, []byte("#!/bin/sh\n"), 0o700))
+
+	t.Run("all checks pass", func(t *testing.T) {
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":      "auth-token",
+			"N8N_RUNNERS_TASK_BROKER_URI": broker.URL,
+			"N8N_RUNNERS_CONFIG_PATH":     writeDoctorConfig(t, dir, runnerPath, freePort(t)),
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.True(t, report.Passed, "unexpected report: %+v", report)
+		checks := checksByName(report)
+		for _, name := range []string{"config", "broker-health", "broker-auth", "broker-websocket", "health-check-ports", "command", "env"} {
+			assert.True(t, checks[name].Passed, "expected check %s to pass: %s", name, checks[name].Detail)
+		}
+		assert.Equal(t, "javascript", checks["env"].RunnerType)
+		assert.Contains(t, checks["env"].Env, "NODE_OPTIONS")
+		assert.Contains(t, checks["env"].Env, "N8N_RUNNERS_GRANT_TOKEN")
+		assert.NotContains(t, checks["env"].Env, "N8N_RUNNERS_AUTH_TOKEN")
+	})
+
+	t.Run("broker on unix socket", func(t *testing.T) {
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":      "auth-token",
+			"N8N_RUNNERS_TASK_BROKER_URI": newFakeSocketBroker(t, "auth-token"),
+			"N8N_RUNNERS_CONFIG_PATH":     writeDoctorConfig(t, dir, runnerPath, freePort(t)),
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.True(t, report.Passed, "unexpected report: %+v", report)
+		checks := checksByName(report)
+		for _, name := range []string{"broker-health", "broker-auth", "broker-websocket"} {
+			assert.True(t, checks[name].Passed, "expected check %s to pass: %s", name, checks[name].Detail)
+		}
+	})
+
+	t.Run("multiple task brokers", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":       "auth-token",
+			"N8N_RUNNERS_TASK_BROKER_URIS": broker.URL + "," + down.URL,
+			"N8N_RUNNERS_CONFIG_PATH":      writeDoctorConfig(t, dir, runnerPath, freePort(t)),
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.False(t, report.Passed)
+		passed := make(map[string]bool)
+		for _, check := range report.Checks {
+			if check.Broker != "" {
+				passed[check.Name+" "+check.Broker] = check.Passed
+			}
+		}
+		assert.Equal(t, map[string]bool{
+			"broker-health " + broker.URL:    true,
+			"broker-auth " + broker.URL:      true,
+			"broker-websocket " + broker.URL: true,
+			"broker-health " + down.URL:      false,
+			"broker-auth " + down.URL:        false,
+			"broker-websocket " + down.URL:   false,
+		}, passed)
+	})
+
+	t.Run("per-runner task broker", func(t *testing.T) {
+		pythonBroker := newFakeBroker(t, "python-token")
+		tokenPath := filepath.Join(dir, "python-token")
+		require.NoError(t, os.WriteFile(tokenPath, []byte("python-token\n"), 0o600))
+
+		configPath := filepath.Join(dir, "config.json")
+		content := `{
+			"task-runners": [{
+				"runner-type": "javascript",
+				"workdir": "` + dir + `",
+				"command": "` + runnerPath + `",
+				"health-check-server-port": "` + freePort(t) + `"
+			}, {
+				"runner-type": "python",
+				"workdir": "` + dir + `",
+				"command": "` + runnerPath + `",
+				"health-check-server-port": "` + freePort(t) + `",
+				"task-broker-uri": "` + pythonBroker.URL + `",
+				"auth-token-file": "` + tokenPath + `"
+			}]
+		}`
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":      "auth-token",
+			"N8N_RUNNERS_TASK_BROKER_URI": broker.URL,
+			"N8N_RUNNERS_CONFIG_PATH":     configPath,
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.True(t, report.Passed, "unexpected report: %+v", report)
+		brokers := make(map[string]int)
+		for _, check := range report.Checks {
+			if check.Broker != "" {
+				brokers[check.Broker]++
+			}
+		}
+		assert.Equal(t, map[string]int{broker.URL: 3, pythonBroker.URL: 3}, brokers)
+	})
+
+	t.Run("failing checks", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		_, port, err := net.SplitHostPort(l.Addr().String())
+		require.NoError(t, err)
+
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":      "wrong-token",
+			"N8N_RUNNERS_TASK_BROKER_URI": broker.URL,
+			"N8N_RUNNERS_CONFIG_PATH":     writeDoctorConfig(t, dir, filepath.Join(dir, "missing"), port),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(ctx, nil, lookuper)
+
+		assert.False(t, report.Passed)
+		checks := checksByName(report)
+		assert.True(t, checks["broker-health"].Passed)
+		assert.False(t, checks["broker-auth"].Passed)
+		assert.Equal(t, "skipped, no grant token to connect with", checks["broker-websocket"].Detail)
+		assert.False(t, checks["health-check-ports"].Passed)
+		assert.Contains(t, checks["health-check-ports"].Detail, port)
+		assert.False(t, checks["command"].Passed)
+		assert.True(t, checks["env"].Passed)
+	})
+
+	t.Run("broker not ready", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":      "auth-token",
+			"N8N_RUNNERS_TASK_BROKER_URI": down.URL,
+			"N8N_RUNNERS_CONFIG_PATH":     writeDoctorConfig(t, dir, runnerPath, freePort(t)),
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.False(t, report.Passed)
+		checks := checksByName(report)
+		assert.False(t, checks["broker-health"].Passed)
+		assert.Equal(t, "skipped, task broker is not ready", checks["broker-auth"].Detail)
+		assert.Equal(t, "skipped, task broker is not ready", checks["broker-websocket"].Detail)
+		assert.True(t, checks["command"].Passed)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		lookuper := envconfig.MapLookuper(map[string]string{
+			"N8N_RUNNERS_AUTH_TOKEN":  "auth-token",
+			"N8N_RUNNERS_CONFIG_PATH": filepath.Join(dir, "missing.json"),
+		})
+
+		report := NewDoctorCommand(logs.NewLogger(logs.ErrorLevel, "")).Execute(context.Background(), nil, lookuper)
+
+		assert.False(t, report.Passed)
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "config", report.Checks[0].Name)
+		assert.Contains(t, report.Checks[0].Detail, "failed to open config file")
+	})
+}
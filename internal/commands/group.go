@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// group runs goroutines sharing a context that is cancelled as soon as any of
+// them fails, and collects the errors of all goroutines.
+type group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	errs   []error
+}
+
+func newGroup(ctx context.Context) *group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &group{ctx: ctx, cancel: cancel}
+}
+
+// Go runs the given function in a goroutine, passing it the group's context.
+func (g *group) Go(fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		if err := fn(g.ctx); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			g.cancel()
+		}
+	}()
+}
+
+// Wait blocks until all goroutines have returned and returns their errors.
+func (g *group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	return errors.Join(g.errs...)
+}
+
+// slots holds the instance numbers of a runner type that are free for use, so
+// as to limit how many runners of that type may be running at the same time.
+type slots chan int
+
+// newSlots creates slots for the instance numbers in the range [from, to).
+func newSlots(from, to int) slots {
+	s := make(slots, max(0, to-from))
+	for i := from; i < to; i++ {
+		s <- i
+	}
+
+	return s
+}
+
+// acquire blocks until an instance number is free, or until the context is
+// cancelled, in which case it returns false.
+func (s slots) acquire(ctx context.Context) (int, bool) {
+	select {
+	case <-ctx.Done():
+		return 0, false
+	case instance := <-s:
+		return instance, true
+	}
+}
+
+func (s slots) release(instance int) {
+	s <- instance
+}
+
+// ports holds the health check server ports of a runner instance that are free
+// for use. A recycled runner keeps its port while draining, so an instance of a
+// recycled runner type has a spare port for its replacement.
+type ports chan string
+
+func newPorts(portNumbers []string) ports {
+	p := make(ports, len(portNumbers))
+	for _, port := range portNumbers {
+		p <- port
+	}
+
+	return p
+}
+
+// acquire blocks until a port is free, or until the context is cancelled, in
+// which case it returns false.
+func (p ports) acquire(ctx context.Context) (string, bool) {
+	select {
+	case <-ctx.Done():
+		return "", false
+	case port := <-p:
+		return port, true
+	}
+}
+
+func (p ports) release(port string) {
+	p <- port
+}
+
+// gate holds whether launching runners of a runner type is paused. While paused,
+// no runners are launched and no task offers are sent, but running runners keep
+// running.
+type gate struct {
+	mu      sync.Mutex
+	paused  chan struct{} // closed while paused
+	resumed chan struct{} // closed while not paused
+}
+
+func newGate() *gate {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &gate{paused: make(chan struct{}), resumed: resumed}
+}
+
+// pause pauses the gate, returning false if it was already paused.
+func (g *gate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.paused:
+		return false
+	default:
+	}
+
+	close(g.paused)
+	g.resumed = make(chan struct{})
+
+	return true
+}
+
+// resume resumes the gate, returning false if it was not paused.
+func (g *gate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+		return false
+	default:
+	}
+
+	close(g.resumed)
+	g.paused = make(chan struct{})
+
+	return true
+}
+
+// wait blocks until the gate is not paused, or until the context is cancelled,
+// in which case it returns false.
+func (g *gate) wait(ctx context.Context) bool {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-resumed:
+		return true
+	}
+}
+
+// whileResumed returns a context that is cancelled as soon as the gate is
+// paused, or the given context is cancelled.
+func (g *gate) whileResumed(ctx context.Context) (context.Context, context.CancelFunc) {
+	g.mu.Lock()
+	paused := g.paused
+	g.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-paused:
+			cancel()
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupCancelsOnFailure(t *testing.T) {
+	g := newGroup(context.Background())
+
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done() // stopped by failure of sibling
+		return nil
+	})
+
+	g.Go(func(_ context.Context) error {
+		return errors.New("fatal error")
+	})
+
+	assert.EqualError(t, g.Wait(), "fatal error")
+}
+
+func TestGroupWithoutFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := newGroup(ctx)
+
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	cancel()
+
+	assert.NoError(t, g.Wait())
+}
+
+func TestSlots(t *testing.T) {
+	free := newSlots(1, 3)
+
+	ctx := context.Background()
+
+	first, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 1, first)
+
+	second, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, 2, second)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, ok = free.acquire(timeoutCtx)
+	assert.False(t, ok, "Expected no free slot")
+
+	free.release(first)
+
+	instance, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, first, instance)
+}
+
+func TestPorts(t *testing.T) {
+	free := newPorts([]string{"5681", "5682"})
+
+	ctx := context.Background()
+
+	draining, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "5681", draining)
+
+	replacement, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "5682", replacement, "Expected spare port while first port is held")
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, ok = free.acquire(timeoutCtx)
+	assert.False(t, ok, "Expected no free port")
+
+	free.release(draining)
+
+	port, ok := free.acquire(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, draining, port)
+}
+
+func TestGate(t *testing.T) {
+	g := newGate()
+	ctx := context.Background()
+
+	assert.True(t, g.wait(ctx), "Expected new gate not to be paused")
+	assert.False(t, g.resume(), "Expected resuming a gate that is not paused to be a no-op")
+
+	offerCtx, cancelOffer := g.whileResumed(ctx)
+	defer cancelOffer()
+
+	assert.True(t, g.pause())
+	assert.False(t, g.pause(), "Expected pausing a paused gate to be a no-op")
+
+	select {
+	case <-offerCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected context to be cancelled on pause")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, g.wait(timeoutCtx), "Expected paused gate to block")
+
+	resumed := make(chan bool)
+	go func() { resumed <- g.wait(ctx) }()
+
+	assert.True(t, g.resume())
+	assert.True(t, <-resumed)
+
+	offerCtx, cancelOffer = g.whileResumed(ctx)
+	defer cancelOffer()
+	assert.NoError(t, offerCtx.Err(), "Expected context not to be cancelled while resumed")
+}
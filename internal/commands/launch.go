@@ -2,16 +2,27 @@ package commands
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"slices"
 	"sync"
+	"syscall"
+	"task-runner-launcher/internal/broker"
+	"task-runner-launcher/internal/cgroup"
 	"task-runner-launcher/internal/config"
 	"task-runner-launcher/internal/env"
+	"task-runner-launcher/internal/errorreporting"
 	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/hooks"
 	"task-runner-launcher/internal/http"
 	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/process"
+	"task-runner-launcher/internal/shim"
+	"task-runner-launcher/internal/status"
 	"task-runner-launcher/internal/ws"
 	"time"
 )
@@ -21,112 +32,945 @@ type Command interface {
 }
 
 type LaunchCommand struct {
-	logger *logs.Logger
+	logger   *logs.Logger
+	registry *status.Registry
+	gate     *gate
+
+	// task brokers to fail over between, shared by all instances of the runner type
+	pool *broker.Pool
 }
 
-func NewLaunchCommand(logger *logs.Logger) *LaunchCommand {
-	return &LaunchCommand{logger: logger}
+func NewLaunchCommand(logger *logs.Logger, registry *status.Registry, gate *gate) *LaunchCommand {
+	return &LaunchCommand{logger: logger, registry: registry, gate: gate}
 }
 
-func (c *LaunchCommand) Execute(launcherConfig *config.LauncherConfig, runnerType string) error {
+// Execute runs the launch cycle for a runner type until the context is cancelled,
+// at which point any running runner is asked to terminate and the cycle returns
+// once the runner has exited.
+func (c *LaunchCommand) Execute(ctx context.Context, launcherConfig *config.LauncherConfig, runnerType string) error {
 	c.logger.Info("Starting launcher goroutine...")
 
-	baseConfig := launcherConfig.BaseConfig
+	baseConfig := launcherConfig.RunnerBaseConfig(runnerType)
 	runnerConfig := launcherConfig.RunnerConfigs[runnerType]
 
-	// 1. change into working directory
+	// 1. check working directory, which each runner is started in, unless the
+	// working directory is within the runner's sandbox
 
-	if err := os.Chdir(runnerConfig.WorkDir); err != nil {
-		return fmt.Errorf("failed to chdir into configured dir (%s): %w", runnerConfig.WorkDir, err)
+	if runnerConfig.Sandbox == nil {
+		info, err := os.Stat(runnerConfig.WorkDir)
+		if err == nil && !info.IsDir() {
+			err = errors.New("not a directory")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to access configured dir (%s): %w", runnerConfig.WorkDir, err)
+		}
 	}
 
-	c.logger.Debugf("Changed into working directory: %s", runnerConfig.WorkDir)
+	// 2. prepare transports to task brokers and env vars to pass to runner
 
-	// 2. prepare env vars to pass to runner
+	pool, err := broker.NewPool(baseConfig)
+	if err != nil {
+		return fmt.Errorf("failed to prepare transport to task broker: %w", err)
+	}
+	c.pool = pool
+	defer pool.Close()
 
 	runnerEnv := env.PrepareRunnerEnv(baseConfig, runnerConfig, c.logger)
-	runnerServerURI := fmt.Sprintf("http://%s:%s", baseConfig.RunnerHealthCheckServerHost, runnerConfig.HealthCheckServerPort)
 
+	// 3. keep warm runners and launch further runners on demand, up to max concurrency,
+	// backing off from relaunching runners that keep failing to launch
+
+	c.registry.Update(runnerType, func(runner *status.Runner) {
+		runner.Instances = make([]status.Instance, runnerConfig.MaxInstances())
+		for i := range runner.Instances {
+			runner.Instances[i].State = status.StateIdle
+		}
+	})
+
+	g := newGroup(ctx)
+	instancePorts := make([]ports, runnerConfig.MaxInstances())
+	for instance := range instancePorts {
+		instancePorts[instance] = newPorts(runnerConfig.InstanceHealthCheckServerPorts(instance))
+	}
+	crashes := newCrashLoop(
+		runnerType,
+		baseConfig.CrashThreshold,
+		time.Duration(baseConfig.CrashCooldown)*time.Second,
+		c.registry,
+		c.logger,
+	)
+
+	if runnerConfig.MinIdle > 0 {
+		c.logger.Infof("Keeping %d warm runner(s) ready for tasks", runnerConfig.MinIdle)
+
+		warmEnv := env.Clear(runnerEnv, env.EnvVarAutoShutdownTimeout)
+		warmEnv = append(warmEnv, fmt.Sprintf("%s=0", env.EnvVarAutoShutdownTimeout))
+
+		for instance := 0; instance < runnerConfig.MinIdle; instance++ {
+			g.Go(func(ctx context.Context) error {
+				return c.keepWarm(ctx, launcherConfig, runnerType, warmEnv, instance, instancePorts[instance], crashes, g)
+			})
+		}
+	}
+
+	// instances not kept warm are free for on-demand runners
+	free := newSlots(runnerConfig.MinIdle, runnerConfig.MaxInstances())
+
+	g.Go(func(ctx context.Context) error {
+		return c.launchOnDemand(ctx, launcherConfig, runnerType, runnerEnv, free, instancePorts, crashes, g)
+	})
+
+	return g.Wait()
+}
+
+// launchOnDemand keeps a task offer registered with the task broker while an
+// instance is free, and launches a runner as that instance whenever the offer
+// is accepted, until the context is cancelled. While the runner type is paused,
+// any pending offer is withdrawn.
+func (c *LaunchCommand) launchOnDemand(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	runnerEnv []string,
+	free slots,
+	instancePorts []ports,
+	crashes *crashLoop,
+	g *group,
+) error {
 	for {
-		// 3. check until task broker is ready
+		// 1. wait until not paused and fewer than max concurrent runners are running
 
-		if err := http.CheckUntilBrokerReady(baseConfig.TaskBrokerURI, c.logger); err != nil {
-			return fmt.Errorf("encountered error while waiting for broker to be ready: %w", err)
+		if !c.gate.wait(ctx) {
+			return nil
 		}
 
-		// 4. fetch grant token for launcher
+		instance, ok := free.acquire(ctx)
+		if !ok {
+			return nil
+		}
+
+		// 2. prepare the launch before sending a task offer, so that a task is
+		// never accepted for a launch that is then aborted
+
+		if !c.awaitBackoff(ctx, runnerType, instance, crashes) {
+			free.release(instance)
+			return nil
+		}
 
-		launcherGrantToken, err := http.FetchGrantToken(baseConfig.TaskBrokerURI, baseConfig.AuthToken)
+		l, failed, err := c.prepareLaunch(ctx, launcherConfig, runnerType, runnerEnv, instancePorts[instance])
 		if err != nil {
-			return fmt.Errorf("failed to fetch grant token for launcher: %w", err)
+			return err
+		}
+		if l == nil {
+			c.setState(runnerType, instance, status.StateIdle)
+			free.release(instance)
+			if ctx.Err() != nil {
+				return nil
+			}
+			crashes.record(failed)
+			continue
+		}
+
+		// 3. wait for task offer to be accepted, unless paused in the meantime
+
+		offerCtx, cancelOffer := c.gate.whileResumed(ctx)
+		var endpoint *broker.Endpoint
+		for endpoint == nil && offerCtx.Err() == nil {
+			endpoint = c.awaitOffer(offerCtx, launcherConfig, runnerType, instance, crashes)
+		}
+		cancelOffer()
+		if endpoint == nil {
+			c.abandonLaunch(l, instancePorts[instance])
+			c.setState(runnerType, instance, status.StateIdle)
+			free.release(instance)
+			if ctx.Err() != nil {
+				return nil
+			}
+			continue
 		}
 
-		c.logger.Debug("Fetched grant token for launcher")
+		// 4. launch runner, freeing its instance once it exits
 
-		// 5. connect to main and wait for task offer to be accepted
+		c.logger.Debug("Task ready for pickup, launching runner...")
 
-		handshakeCfg := ws.HandshakeConfig{
-			TaskType:            runnerConfig.RunnerType,
-			TaskBrokerServerURI: launcherConfig.BaseConfig.TaskBrokerURI,
-			GrantToken:          launcherGrantToken,
+		g.Go(func(ctx context.Context) error {
+			defer free.release(instance)
+
+			endpoints := preferring(endpoint, c.pool.Endpoints())
+			failed, err := c.launchRunner(ctx, launcherConfig, runnerType, l, endpoints, instance, instancePorts[instance], g)
+			if err == nil && ctx.Err() == nil {
+				crashes.record(failed)
+			}
+
+			return err
+		})
+	}
+}
+
+// awaitOffer waits until the runner type may be launched again and any task
+// broker is ready, connects to the first ready task broker and waits for a task
+// offer to be accepted, failing over to the next ready task broker on any
+// failure to fetch a grant token from or complete the handshake with it.
+// Returns the task broker that accepted the offer, or nil if none did.
+func (c *LaunchCommand) awaitOffer(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	instance int,
+	crashes *crashLoop,
+) *broker.Endpoint {
+	// 1. back off from a runner type that keeps failing to launch, and check
+	// until any task broker is ready
+
+	ready := c.awaitBroker(ctx, runnerType, instance, crashes)
+
+	// 2. send task offer to each ready task broker in turn, until one accepts it
+
+	for i, endpoint := range ready {
+		if i > 0 {
+			c.logger.Warnf("Failing over to task broker %s...", endpoint.URI)
 		}
 
-		err = ws.Handshake(handshakeCfg, c.logger)
+		accepted, err := c.offer(ctx, launcherConfig, runnerType, instance, endpoint)
 		switch {
+		case ctx.Err() != nil:
+			return nil
 		case errors.Is(err, errs.ErrServerDown):
-			c.logger.Warn("Task broker is down, launcher will try to reconnect...")
-			time.Sleep(time.Second * 5)
-			continue // back to checking until broker ready
+			c.logger.Warnf("Task broker %s is down: %v", endpoint.URI, err)
+			continue
 		case err != nil:
-			return fmt.Errorf("handshake failed: %w", err)
+			c.logger.Warnf("Task broker %s failed: %v", endpoint.URI, err)
+			continue
 		}
 
-		// 6. fetch grant token for runner
+		if accepted {
+			return endpoint
+		}
+	}
 
-		runnerGrantToken, err := http.FetchGrantToken(baseConfig.TaskBrokerURI, baseConfig.AuthToken)
+	if len(ready) > 0 {
+		c.logger.Warn("All task brokers failed, launcher will try to reconnect...")
+		c.setState(runnerType, instance, status.StateWaitingForBroker)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second * 5):
+		}
+	}
+
+	return nil // back to checking until broker ready
+}
+
+// offer fetches a grant token from the task broker, connects to the task broker
+// and waits for a task offer to be accepted. Returns whether the offer was
+// accepted, or an error if the handshake failed, wrapping errs.ErrServerDown if
+// the task broker is down.
+func (c *LaunchCommand) offer(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	instance int,
+	endpoint *broker.Endpoint,
+) (bool, error) {
+	baseConfig := launcherConfig.RunnerBaseConfig(runnerType)
+	runnerConfig := launcherConfig.RunnerConfigs[runnerType]
+
+	// 1. fetch grant token for launcher
+
+	c.setState(runnerType, instance, status.StateHandshaking)
+	launcherGrantToken, err := http.FetchGrantTokenOnce(ctx, endpoint.Transport, endpoint.URI, baseConfig.AuthToken)
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch grant token for launcher: %w", err)
+	}
+
+	c.logger.Debug("Fetched grant token for launcher")
+
+	// 2. connect to main and wait for task offer to be accepted
+
+	handshakeCfg := ws.HandshakeConfig{
+		TaskType:            runnerConfig.RunnerType,
+		TaskBrokerServerURI: endpoint.URI,
+		GrantToken:          launcherGrantToken,
+		Transport:           endpoint.Transport,
+		OnOfferSent: func() {
+			c.setState(runnerType, instance, status.StateOfferPending)
+			c.attach(runnerType, endpoint)
+		},
+	}
+
+	err = ws.Handshake(ctx, handshakeCfg, c.logger)
+	switch {
+	case ctx.Err() != nil:
+		return false, nil
+	case errors.Is(err, errs.ErrServerDown):
+		return false, err
+	case err != nil:
+		return false, fmt.Errorf("handshake failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// awaitBroker waits until the runner type may be launched again after any failed
+// launches, and until any task broker is ready. Returns the ready task brokers
+// in the order to try them in, or none if the context is cancelled first.
+func (c *LaunchCommand) awaitBroker(
+	ctx context.Context,
+	runnerType string,
+	instance int,
+	crashes *crashLoop,
+) []*broker.Endpoint {
+	if !c.awaitBackoff(ctx, runnerType, instance, crashes) {
+		return nil
+	}
+
+	// readiness is checked until the context is cancelled, so an error means the
+	// context was cancelled
+	c.setState(runnerType, instance, status.StateWaitingForBroker)
+	ready, _ := http.CheckUntilBrokerReady(ctx, c.pool.Endpoints(), c.logger)
+
+	return ready
+}
+
+// awaitBackoff waits until the runner type may be launched again after any
+// failed launches. Returns false if the context is cancelled first.
+func (c *LaunchCommand) awaitBackoff(ctx context.Context, runnerType string, instance int, crashes *crashLoop) bool {
+	if crashes.isBackingOff() {
+		c.setState(runnerType, instance, status.StateBackingOff)
+	}
+
+	return crashes.wait(ctx)
+}
+
+// keepWarm repeatedly launches a runner as the given instance as soon as any
+// task broker is ready, attached to the first ready task broker to grant it a
+// token, until the context is cancelled. Warm runners are started and
+// registered with the task broker without waiting for a task offer to be
+// accepted, and do not shut down on idle timeout. While the runner type is paused, no warm runner is launched.
+func (c *LaunchCommand) keepWarm(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	runnerEnv []string,
+	instance int,
+	instancePorts ports,
+	crashes *crashLoop,
+	g *group,
+) error {
+	for {
+		if !c.gate.wait(ctx) {
+			return nil
+		}
+
+		readyCtx, cancelReady := c.gate.whileResumed(ctx)
+		ready := c.awaitBroker(readyCtx, runnerType, instance, crashes)
+		cancelReady()
+		if len(ready) == 0 {
+			if ctx.Err() != nil {
+				return nil
+			}
+			c.setState(runnerType, instance, status.StateIdle)
+			continue // paused in the meantime
+		}
+
+		c.logger.Debug("Launching warm runner...")
+
+		l, failed, err := c.prepareLaunch(ctx, launcherConfig, runnerType, runnerEnv, instancePorts)
 		if err != nil {
-			return fmt.Errorf("failed to fetch grant token for runner: %w", err)
+			return err
+		}
+		if l != nil {
+			failed, err = c.launchRunner(ctx, launcherConfig, runnerType, l, ready, instance, instancePorts, g)
+			if err != nil {
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
 		}
 
-		c.logger.Debug("Fetched grant token for runner")
+		crashes.record(failed)
 
-		runnerEnv = append(runnerEnv, fmt.Sprintf("N8N_RUNNERS_GRANT_TOKEN=%s", runnerGrantToken))
+		c.logger.Info("Replacing warm runner...")
+	}
+}
+
+// launch is a runner launch prepared before a task offer is sent for it.
+type launch struct {
+	id         string
+	port       string
+	runnerEnv  []string
+	hookEnv    []string
+	scratchDir string
+}
 
-		// 8. launch runner
+// prepareLaunch prepares launching a runner at a free port of the instance:
+// it creates any scratch dir, runs any pre-launch hook and checks the runner's
+// files against their pinned hashes. Returns the prepared launch, or nil and
+// whether the launch failed, i.e. the pre-launch hook or the integrity check
+// failed, or an error only if the launch could not be prepared.
+func (c *LaunchCommand) prepareLaunch(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	runnerEnv []string,
+	instancePorts ports,
+) (*launch, bool, error) {
+	runnerConfig := launcherConfig.RunnerConfigs[runnerType]
 
-		c.logger.Debug("Task ready for pickup, launching runner...")
-		c.logger.Debugf("Command: %s", runnerConfig.Command)
-		c.logger.Debugf("Args: %v", runnerConfig.Args)
+	// 1. wait for a free port, in case a recycled runner is still draining
+
+	port, ok := instancePorts.acquire(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	l := &launch{id: randomID(), port: port}
+	l.runnerEnv = env.Clear(runnerEnv, env.EnvVarHealthCheckServerPort)
+	l.runnerEnv = append(l.runnerEnv, fmt.Sprintf("%s=%s", env.EnvVarHealthCheckServerPort, port))
+	l.hookEnv = append(slices.Clone(l.runnerEnv),
+		fmt.Sprintf("%s=%s", hooks.EnvVarRunnerType, runnerType),
+		fmt.Sprintf("%s=%s", hooks.EnvVarLaunchID, l.id),
+	)
+
+	c.logger.Debugf("Launch ID: %s", l.id)
+
+	// 2. prepare scratch dir and run pre-launch hook
+
+	if runnerConfig.ScratchDir {
+		scratchDir, dir, err := createScratchDir(runnerConfig)
+		if err != nil {
+			instancePorts.release(port)
+			return nil, false, fmt.Errorf("failed to create scratch dir for runner: %w", err)
+		}
+		l.scratchDir = scratchDir
+		l.runnerEnv = append(l.runnerEnv, fmt.Sprintf("%s=%s", env.EnvVarScratchDir, dir))
+		l.hookEnv = append(l.hookEnv, fmt.Sprintf("%s=%s", env.EnvVarScratchDir, scratchDir))
+		c.logger.Debugf("Created scratch dir %s for runner", scratchDir)
+	}
+
+	if runnerConfig.PreLaunch != nil {
+		if err := c.runHook(ctx, launcherConfig, runnerType, hooks.PreLaunch, runnerConfig.PreLaunch, l.hookEnv); err != nil {
+			c.abandonLaunch(l, instancePorts)
+			if ctx.Err() != nil {
+				return nil, false, nil
+			}
+			c.logger.Errorf("Aborted launch on failure of pre-launch hook: %v", err)
+			return nil, true, nil
+		}
+	}
+
+	// 3. check the runner's files, after any pre-launch hook that may update them
+
+	if !c.verifyIntegrity(runnerType, runnerConfig) {
+		c.abandonLaunch(l, instancePorts)
+		return nil, true, nil
+	}
+
+	return l, false, nil
+}
+
+// abandonLaunch cleans up after a prepared launch that is not going ahead.
+func (c *LaunchCommand) abandonLaunch(l *launch, instancePorts ports) {
+	c.removeScratchDir(l.scratchDir)
+	instancePorts.release(l.port)
+}
+
+// launchRunner fetches a grant token for a runner from the first of the given
+// task brokers to grant one, launches the prepared runner attached to that task
+// broker as the given instance, and monitors its health until it exits or is
+// recycled. A recycled runner is left draining in the background of the group,
+// keeping its port until it has exited. Returns whether the launch failed, i.e.
+// no task broker granted a token or the runner exited with an error within the
+// crash window, or an error only if the runner could not be launched.
+func (c *LaunchCommand) launchRunner(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	l *launch,
+	endpoints []*broker.Endpoint,
+	instance int,
+	instancePorts ports,
+	g *group,
+) (bool, error) {
+	baseConfig := launcherConfig.RunnerBaseConfig(runnerType)
+	runnerConfig := launcherConfig.RunnerConfigs[runnerType]
+
+	gracePeriod := time.Duration(baseConfig.GracePeriod) * time.Second
+	crashWindow := time.Duration(baseConfig.CrashWindow) * time.Second
+
+	port, scratchDir := l.port, l.scratchDir
+	runnerEnv, hookEnv := l.runnerEnv, l.hookEnv
+	runnerServerURI := fmt.Sprintf("http://%s:%s", baseConfig.RunnerHealthCheckServerHost, port)
+
+	isStarted := false
+	defer func() {
+		if !isStarted {
+			c.abandonLaunch(l, instancePorts)
+		}
+	}()
 
-		ctx, cancelHealthMonitor := context.WithCancel(context.Background())
-		var wg sync.WaitGroup
+	// 1. fetch grant token for runner
 
-		cmd := exec.CommandContext(ctx, runnerConfig.Command, runnerConfig.Args...)
-		cmd.Env = runnerEnv
-		runnerPrefix := logs.GetRunnerPrefix(runnerType)
-		logLevel := logs.ParseLevel(launcherConfig.BaseConfig.LogLevel)
-		cmd.Stdout, cmd.Stderr = logs.GetRunnerWriters(logLevel, runnerPrefix)
+	endpoint, runnerGrantToken := c.fetchRunnerGrantToken(ctx, baseConfig.AuthToken, endpoints)
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if endpoint == nil {
+		c.logger.Error("Aborted launch on no task broker granting a token for runner")
+		return true, nil
+	}
+
+	c.logger.Debug("Fetched grant token for runner")
+
+	runnerEnv = append(runnerEnv, fmt.Sprintf("%s=%s", env.EnvVarGrantToken, runnerGrantToken))
+	runnerEnv = env.Clear(runnerEnv, env.EnvVarTaskBrokerURI)
+	runnerEnv = append(runnerEnv, fmt.Sprintf("%s=%s", env.EnvVarTaskBrokerURI, endpoint.RunnerURI))
+
+	// 2. launch runner
+
+	c.logger.Debugf("Command: %s", runnerConfig.Command)
+	c.logger.Debugf("Args: %v", runnerConfig.Args)
+
+	healthCtx, cancelHealthMonitor := context.WithCancel(ctx)
+	var wg sync.WaitGroup
 
-		if err := cmd.Start(); err != nil {
+	cmd := process.Command(runnerConfig.Command, runnerConfig.Args...)
+	cmd.Env = runnerEnv
+	cmd.SysProcAttr.Credential = runnerConfig.Credential
+	if runnerConfig.Sandbox == nil {
+		cmd.Dir = runnerConfig.WorkDir
+	}
+	if len(runnerConfig.Rlimits) > 0 || runnerConfig.Sandbox != nil || runnerConfig.Seccomp != nil {
+		spec := shim.Spec{Rlimits: runnerConfig.Rlimits, Sandbox: runnerConfig.Sandbox}
+		if runnerConfig.Sandbox != nil {
+			spec.Dir = runnerConfig.WorkDir
+		}
+		if runnerConfig.Seccomp != nil {
+			spec.Seccomp = runnerConfig.Seccomp.ParsedProfile
+			spec.SeccompLogOnly = runnerConfig.Seccomp.LogOnly
+		}
+		if err := shim.Wrap(cmd, spec); err != nil {
 			cancelHealthMonitor()
-			return fmt.Errorf("failed to start runner process: %w", err)
+			return false, fmt.Errorf("failed to prepare runner process: %w", err)
 		}
+	}
+	runnerPrefix := logs.GetRunnerPrefix(runnerType)
+	if runnerConfig.MaxInstances() > 1 {
+		runnerPrefix = logs.GetRunnerInstancePrefix(runnerType, instance+1)
+	}
+	logLevel := logs.ParseLevel(launcherConfig.BaseConfig.LogLevel)
+	stdout, stderr := logs.GetRunnerWriters(logLevel, runnerPrefix)
 
-		go http.ManageRunnerHealth(ctx, cmd, runnerServerURI, &wg, c.logger)
+	var cg *cgroup.Cgroup
+	if runnerConfig.Cgroup != nil {
+		var err error
+		cg, err = cgroup.Create("runner-"+runnerType, runnerConfig.Cgroup)
+		if err != nil {
+			c.logger.Warnf("Launching runner without cgroup limits: %v", err)
+		} else {
+			cg.Apply(cmd.SysProcAttr)
+			c.logger.Debugf("Created cgroup %s for runner", cg.Path())
+		}
+	}
 
-		err = cmd.Wait()
-		if err != nil && err.Error() == "signal: killed" {
-			c.logger.Warn("Unresponsive runner process was terminated")
-		} else if err != nil {
-			c.logger.Errorf("Runner process exited with error: %v", err)
+	runner, err := process.Start(cmd, stdout, stderr)
+	if err != nil {
+		cancelHealthMonitor()
+		c.removeCgroup(cg)
+		return false, fmt.Errorf("failed to start runner process: %w", err)
+	}
+	startedAt := time.Now()
+	isStarted = true
+	pid := runner.Pid()
+	c.registry.UpdateInstance(runnerType, instance, func(i *status.Instance) {
+		*i = status.Instance{State: status.StateRunning, PID: pid, StartedAt: &startedAt, Broker: endpoint.URI}
+	})
+	c.attach(runnerType, endpoint)
+
+	terminateUnhealthy := func() error {
+		return c.terminate(runner, status.KillReasonUnhealthy, gracePeriod)
+	}
+	go http.ManageRunnerHealth(healthCtx, terminateUnhealthy, runnerServerURI, &wg, c.logger)
+
+	exited := make(chan struct{})
+	recycled := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.superviseRunner(ctx, runner, runnerConfig, gracePeriod, exited, recycled, cancelHealthMonitor)
+	}()
+
+	// 3. wait for runner's process tree to exit, or for the runner to be recycled,
+	// then run post-exit hook
+
+	failedChan := make(chan bool, 1)
+	g.Go(func(_ context.Context) error {
+		defer instancePorts.release(port)
+
+		err := runner.Wait()
+		close(exited)
+		c.clearInstance(runnerType, instance, pid)
+
+		failed := true
+		postExitEnv := slices.Clone(hookEnv)
+		if cmd.ProcessState == nil {
+			c.logger.Errorf("Failed to wait for runner process: %v", err)
 		} else {
-			c.logger.Info("Runner process exited on idle timeout")
+			exit := status.NewRunnerExit(cmd.ProcessState, startedAt)
+			exit.ExceededRlimit, _ = shim.ExceededRlimit(cmd.ProcessState, runnerConfig.Sandbox != nil)
+			exit.OOMKilled = cg != nil && cg.OOMKilled()
+			if reason := runner.TerminateReason(); reason != "" {
+				exit.KilledByLauncher, exit.KillReason = true, status.KillReason(reason)
+			} else if ctx.Err() != nil {
+				exit.KilledByLauncher, exit.KillReason = true, stopReason(ctx)
+			}
+
+			c.logExit(exit, runnerConfig)
+			errorreporting.ReportRunnerExit(runnerType, exit)
+			c.registry.Update(runnerType, func(runner *status.Runner) {
+				runner.LastExit = &exit
+			})
+
+			failed = exit.Failed() && exit.Duration < crashWindow
+			postExitEnv = append(postExitEnv,
+				fmt.Sprintf("%s=%d", hooks.EnvVarExitCode, exit.ExitCode),
+				fmt.Sprintf("%s=%s", hooks.EnvVarExitSignal, exit.Signal),
+				fmt.Sprintf("%s=%s", hooks.EnvVarKillReason, exit.KillReason),
+			)
 		}
+
 		cancelHealthMonitor()
+		c.removeCgroup(cg)
+
+		// run to completion even on shutdown, as the hook may clean up after the runner
+		if runnerConfig.PostExit != nil {
+			hookCtx := context.WithoutCancel(ctx)
+			if err := c.runHook(hookCtx, launcherConfig, runnerType, hooks.PostExit, runnerConfig.PostExit, postExitEnv); err != nil {
+				c.logger.Warnf("Post-exit hook failed: %v", err)
+			}
+		}
+
+		c.removeScratchDir(scratchDir)
 
 		wg.Wait()
 
-		// next runner will need to fetch a new grant token
-		runnerEnv = env.Clear(runnerEnv, env.EnvVarGrantToken)
+		failedChan <- failed
+
+		return nil
+	})
+
+	select {
+	case failed := <-failedChan:
+		return failed, nil
+	case <-recycled:
+		c.clearInstance(runnerType, instance, pid)
+		return false, nil
+	}
+}
+
+// fetchRunnerGrantToken fetches a grant token for a runner from each of the given
+// task brokers in turn, until one grants it. Returns the task broker that granted
+// the token along with the token, or nil if none did.
+func (c *LaunchCommand) fetchRunnerGrantToken(
+	ctx context.Context,
+	authToken string,
+	endpoints []*broker.Endpoint,
+) (*broker.Endpoint, string) {
+	for i, endpoint := range endpoints {
+		if i > 0 {
+			c.logger.Warnf("Failing over to task broker %s...", endpoint.URI)
+		}
+
+		token, err := http.FetchGrantTokenOnce(ctx, endpoint.Transport, endpoint.URI, authToken)
+		if ctx.Err() != nil {
+			return nil, ""
+		}
+		if err != nil {
+			c.logger.Warnf("Task broker %s failed to grant token for runner: %v", endpoint.URI, err)
+			continue
+		}
+
+		return endpoint, token
+	}
+
+	return nil, ""
+}
+
+// superviseRunner terminates a runner on shutdown, drains it on reaching its max
+// lifetime, and terminates it, or kills it if draining, on reaching its max
+// launch duration, until the runner has exited. Draining a runner sends it
+// SIGTERM, closes `recycled` and stops monitoring its health, so that a
+// replacement can take its place, while the runner itself is left to finish its
+// in-flight tasks and exit.
+func (c *LaunchCommand) superviseRunner(
+	ctx context.Context,
+	runner *process.Group,
+	runnerConfig *config.RunnerConfig,
+	gracePeriod time.Duration,
+	exited <-chan struct{},
+	recycled chan<- struct{},
+	stopHealthMonitor context.CancelFunc,
+) {
+	maxLifetime := time.Duration(runnerConfig.MaxLifetime) * time.Second
+	maxLaunchDuration := time.Duration(runnerConfig.MaxLaunchDuration) * time.Second
+
+	var lifetimeReached, launchDurationReached <-chan time.Time
+	if maxLifetime > 0 {
+		timer := time.NewTimer(maxLifetime)
+		defer timer.Stop()
+		lifetimeReached = timer.C
+	}
+	if maxLaunchDuration > 0 {
+		timer := time.NewTimer(maxLaunchDuration)
+		defer timer.Stop()
+		launchDurationReached = timer.C
+	}
+
+	isDraining := false
+
+	// terminations run alongside, so that shutdown still takes effect while a
+	// runner is draining
+	var terminations sync.WaitGroup
+	defer terminations.Wait()
+
+	terminate := func(reason status.KillReason, gracePeriod time.Duration) {
+		terminations.Add(1)
+		go func() {
+			defer terminations.Done()
+			if err := c.terminate(runner, reason, gracePeriod); err != nil {
+				c.logger.Errorf("Failed to terminate runner process (%s): %v", reason, err)
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-exited:
+			return
+
+		case <-ctx.Done():
+			terminate(stopReason(ctx), gracePeriod)
+			<-exited
+			return
+
+		case <-lifetimeReached:
+			lifetimeReached = nil
+			c.logger.Infof("Runner reached its max lifetime of %v, sending SIGTERM to drain runner...", maxLifetime)
+			stopHealthMonitor()
+			close(recycled)
+
+			// On SIGTERM, a runner stops accepting tasks and exits once it has
+			// finished its in-flight tasks, so a draining runner is left to exit
+			// on its own until its max launch duration.
+			if err := runner.Drain(string(status.KillReasonRecycled)); err != nil {
+				c.logger.Errorf("Failed to drain runner process: %v", err)
+			}
+			isDraining = true
+
+		case <-launchDurationReached:
+			launchDurationReached = nil
+			if isDraining {
+				c.logger.Warnf("Recycled runner has not finished draining by its max launch duration of %v, killing runner...", maxLaunchDuration)
+				if err := runner.Signal(syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+					c.logger.Errorf("Failed to kill runner process: %v", err)
+				}
+				continue
+			}
+			c.logger.Warnf("Runner reached its max launch duration of %v, terminating runner...", maxLaunchDuration)
+			terminate(status.KillReasonExpired, gracePeriod)
+		}
+	}
+}
+
+// stopReason returns why the launch cycle was stopped, i.e. on a config reload
+// that changed the runner config, on an admin action, or else on shutdown.
+func stopReason(ctx context.Context) status.KillReason {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errConfigReloaded):
+		return status.KillReasonReloaded
+	case errors.Is(cause, errRestarted):
+		return status.KillReasonRestarted
+	case errors.Is(cause, errDrained):
+		return status.KillReasonDrained
+	default:
+		return status.KillReasonShutdown
+	}
+}
+
+// terminate asks a runner's process tree to exit by sending it SIGTERM, and
+// kills the process tree if the runner has not exited within the grace period.
+func (c *LaunchCommand) terminate(runner *process.Group, reason status.KillReason, gracePeriod time.Duration) error {
+	c.logger.Infof("Sending SIGTERM to runner (%s), waiting up to %v for it to exit...", reason, gracePeriod)
+
+	return runner.Terminate(string(reason), gracePeriod)
+}
+
+// logExit logs how a runner process exited, at a level matching its cause.
+func (c *LaunchCommand) logExit(exit status.RunnerExit, runnerConfig *config.RunnerConfig) {
+	switch {
+	case exit.KillReason == status.KillReasonShutdown:
+		c.logger.Infof("Runner process exited on shutdown (%v)", exit)
+	case exit.KillReason == status.KillReasonReloaded:
+		c.logger.Infof("Runner process exited on config reload (%v)", exit)
+	case exit.KillReason == status.KillReasonRestarted:
+		c.logger.Infof("Runner process exited on restart (%v)", exit)
+	case exit.KillReason == status.KillReasonDrained:
+		c.logger.Infof("Runner process exited on drain (%v)", exit)
+	case exit.KillReason == status.KillReasonRecycled:
+		c.logger.Infof("Recycled runner process exited (%v)", exit)
+	case exit.KillReason == status.KillReasonExpired:
+		c.logger.Warnf("Runner process was terminated on reaching its max launch duration (%v)", exit)
+	case exit.KillReason == status.KillReasonUnhealthy:
+		c.logger.Warnf("Unresponsive runner process was terminated (%v)", exit)
+	case exit.OOMKilled:
+		c.logger.Warnf("Runner process was killed on reaching its memory limit of %s (%v)", runnerConfig.Cgroup.MemoryMax, exit)
+	case exit.ExceededRlimit != "":
+		c.logger.Warnf("Runner process was terminated on exceeding its %s limit (%v)", exit.ExceededRlimit, exit)
+	case exit.Signal != "":
+		c.logger.Errorf("Runner process was terminated unexpectedly (%v)", exit)
+	case exit.ExitCode != 0:
+		c.logger.Errorf("Runner process exited with error (%v)", exit)
+	default:
+		c.logger.Infof("Runner process exited on idle timeout (%v)", exit)
+	}
+}
+
+// setState records what the launcher is doing for the given instance.
+func (c *LaunchCommand) setState(runnerType string, instance int, state status.State) {
+	c.registry.UpdateInstance(runnerType, instance, func(i *status.Instance) {
+		i.State = state
+	})
+}
+
+// attach records that the runner type is attached to the given task broker.
+func (c *LaunchCommand) attach(runnerType string, endpoint *broker.Endpoint) {
+	c.registry.Update(runnerType, func(runner *status.Runner) {
+		runner.Broker = endpoint.URI
+	})
+}
+
+// preferring returns the given task brokers with the preferred one moved first.
+func preferring(preferred *broker.Endpoint, endpoints []*broker.Endpoint) []*broker.Endpoint {
+	ordered := []*broker.Endpoint{preferred}
+	for _, endpoint := range endpoints {
+		if endpoint != preferred {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	return ordered
+}
+
+// clearInstance records that the runner with the given PID no longer runs as
+// the given instance, unless a replacement already took its place.
+func (c *LaunchCommand) clearInstance(runnerType string, instance int, pid int) {
+	c.registry.UpdateInstance(runnerType, instance, func(i *status.Instance) {
+		if i.PID == pid {
+			*i = status.Instance{State: status.StateIdle}
+		}
+	})
+}
+
+// verifyIntegrity checks the runner's files against their pinned hashes, if
+// any, recording the outcome in the runner type's status. Returns whether the
+// runner may be launched.
+func (c *LaunchCommand) verifyIntegrity(runnerType string, runnerConfig *config.RunnerConfig) bool {
+	err := runnerConfig.VerifyIntegrity()
+	c.registry.Update(runnerType, func(runner *status.Runner) {
+		runner.IntegrityError = ""
+		if err != nil {
+			runner.IntegrityError = err.Error()
+		}
+	})
+
+	if err != nil {
+		c.logger.Errorf("Refused to launch runner on failed integrity check: %v", err)
+		errorreporting.ReportIntegrityFailure(runnerType, err)
+		return false
+	}
+
+	return true
+}
+
+// removeCgroup removes the cgroup of a runner launch, if any.
+func (c *LaunchCommand) removeCgroup(cg *cgroup.Cgroup) {
+	if cg == nil {
+		return
+	}
+
+	if err := cg.Remove(); err != nil {
+		c.logger.Warnf("Failed to remove runner cgroup: %v", err)
+	}
+}
+
+// runHook runs a hook of a runner launch in the runner's working dir, logging
+// its output with a prefix of its own.
+func (c *LaunchCommand) runHook(
+	ctx context.Context,
+	launcherConfig *config.LauncherConfig,
+	runnerType string,
+	name string,
+	hook *config.HookConfig,
+	hookEnv []string,
+) error {
+	runnerConfig := launcherConfig.RunnerConfigs[runnerType]
+	logLevel := logs.ParseLevel(launcherConfig.BaseConfig.LogLevel)
+	stdout, stderr := logs.GetHookWriters(logLevel, logs.GetHookPrefix(runnerType, name))
+
+	c.logger.Debugf("Running %s hook: %s %v", name, hook.Command, hook.Args)
+
+	hookEnv = append(slices.Clone(hookEnv), fmt.Sprintf("%s=%s", hooks.EnvVarHook, name))
+
+	return hooks.Run(ctx, hook, hostWorkDir(runnerConfig), hookEnv, stdout, stderr)
+}
+
+// hostWorkDir returns the runner's working dir as seen by the launcher, which
+// differs from the configured `workdir` if the runner is sandboxed.
+func hostWorkDir(runnerConfig *config.RunnerConfig) string {
+	if runnerConfig.Sandbox != nil {
+		if dir, _, ok := runnerConfig.Sandbox.HostPath(runnerConfig.WorkDir); ok {
+			return dir
+		}
+	}
+
+	return runnerConfig.WorkDir
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// createScratchDir creates an empty scratch dir under the runner's working dir,
+// owned by the runner's user. Returns the path of the scratch dir, as well as
+// its path as seen by the runner, which differ if the runner is sandboxed.
+func createScratchDir(runnerConfig *config.RunnerConfig) (string, string, error) {
+	scratchDir, err := os.MkdirTemp(hostWorkDir(runnerConfig), "scratch-"+runnerConfig.RunnerType+"-")
+	if err != nil {
+		return "", "", err
+	}
+
+	if cred := runnerConfig.Credential; cred != nil {
+		if err := os.Chown(scratchDir, int(cred.Uid), int(cred.Gid)); err != nil {
+			_ = os.Remove(scratchDir)
+			return "", "", err
+		}
+	}
+
+	return scratchDir, filepath.Join(runnerConfig.WorkDir, filepath.Base(scratchDir)), nil
+}
+
+// removeScratchDir removes the scratch dir of a runner launch, if any.
+func (c *LaunchCommand) removeScratchDir(scratchDir string) {
+	if scratchDir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(scratchDir); err != nil {
+		c.logger.Warnf("Failed to remove runner scratch dir: %v", err)
 	}
 }
@@ -0,0 +1,635 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"task-runner-launcher/internal/broker"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/process"
+	"task-runner-launcher/internal/status"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScratchDir(t *testing.T) {
+	t.Run("under workdir", func(t *testing.T) {
+		workDir := t.TempDir()
+		runnerConfig := &config.RunnerConfig{RunnerType: "javascript", WorkDir: workDir, ScratchDir: true}
+
+		scratchDir, runnerDir, err := createScratchDir(runnerConfig)
+		require.NoError(t, err)
+
+		assert.Equal(t, workDir, filepath.Dir(scratchDir))
+		assert.Equal(t, scratchDir, runnerDir)
+		info, err := os.Stat(scratchDir)
+		require.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+		another, _, err := createScratchDir(runnerConfig)
+		require.NoError(t, err)
+		assert.NotEqual(t, scratchDir, another, "Expected a new scratch dir per launch")
+	})
+
+	t.Run("under sandboxed workdir", func(t *testing.T) {
+		hostDir := t.TempDir()
+		runnerConfig := &config.RunnerConfig{
+			RunnerType: "javascript",
+			WorkDir:    "/app",
+			ScratchDir: true,
+			Sandbox:    &config.SandboxConfig{ReadWriteMounts: []string{hostDir + ":/app"}},
+		}
+
+		scratchDir, runnerDir, err := createScratchDir(runnerConfig)
+		require.NoError(t, err)
+
+		assert.Equal(t, hostDir, filepath.Dir(scratchDir))
+		assert.Equal(t, filepath.Join("/app", filepath.Base(scratchDir)), runnerDir)
+		assert.DirExists(t, scratchDir)
+	})
+}
+
+// newOfferBroker starts a task broker that is ready and hands out grant tokens,
+// and over websocket either accepts the launcher's task offer or, if down,
+// drops the connection once the launcher has sent its info.
+func newOfferBroker(t *testing.T, isDown bool) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /runners/auth", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"token": "grant-token"}}`))
+	})
+	mux.HandleFunc("GET /runners/_ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg map[string]any
+		_ = conn.WriteJSON(map[string]string{"type": "broker:inforequest"})
+		if err := conn.ReadJSON(&msg); err != nil || isDown {
+			return
+		}
+		_ = conn.WriteJSON(map[string]string{"type": "broker:runnerregistered"})
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = conn.WriteJSON(map[string]string{"type": "broker:taskofferaccept", "taskId": "task-id"})
+		_ = conn.ReadJSON(&msg)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAwaitOfferFailsOver(t *testing.T) {
+	down := newOfferBroker(t, true)
+	up := newOfferBroker(t, false)
+
+	launcherConfig := &config.LauncherConfig{
+		BaseConfig: &config.BaseConfig{
+			AuthToken:      "auth-token",
+			Brokers:        []config.Broker{{URI: down.URL}, {URI: up.URL}},
+			CrashThreshold: 5,
+		},
+		RunnerConfigs: map[string]*config.RunnerConfig{
+			"javascript": {RunnerType: "javascript"},
+		},
+	}
+
+	pool, err := broker.NewPool(launcherConfig.BaseConfig)
+	require.NoError(t, err)
+
+	logger := logs.NewLogger(logs.ErrorLevel, "")
+	registry := status.NewRegistry()
+	cmd := NewLaunchCommand(logger, registry, newGate())
+	cmd.pool = pool
+	crashes := newCrashLoop("javascript", 5, time.Minute, registry, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	endpoint := cmd.awaitOffer(ctx, launcherConfig, "javascript", 0, crashes)
+	require.NotNil(t, endpoint, "Expected offer to be accepted")
+
+	assert.Equal(t, up.URL, endpoint.URI, "Expected failover to the task broker that is up")
+	assert.Equal(t, up.URL, registry.Runners()["javascript"].Broker)
+}
+
+// newFailingBroker starts a task broker that passes the readiness check but
+// then fails the offer at the given step: by dropping the connection without a
+// close frame as if killed, on fetching the grant token, on dialing the
+// websocket or on reading from the websocket, or by breaking the protocol, on
+// rejecting the auth token, on handing out a malformed grant token, on
+// rejecting the websocket upgrade or on sending a malformed message.
+func newFailingBroker(t *testing.T, step string) *httptest.Server {
+	t.Helper()
+
+	hangUp := func(w http.ResponseWriter) {
+		if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /runners/auth", func(w http.ResponseWriter, _ *http.Request) {
+		switch step {
+		case "grant token":
+			hangUp(w)
+		case "grant token rejected":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "grant token malformed":
+			_, _ = w.Write([]byte(`{"data":`))
+		default:
+			_, _ = w.Write([]byte(`{"data": {"token": "grant-token"}}`))
+		}
+	})
+	mux.HandleFunc("GET /runners/_ws", func(w http.ResponseWriter, r *http.Request) {
+		switch step {
+		case "websocket dial":
+			hangUp(w)
+			return
+		case "websocket rejected":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		if step == "websocket malformed" {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+			_, _, _ = conn.ReadMessage()
+			return
+		}
+		conn.NetConn().Close()
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAwaitOfferFailsOverOnBrokerFailure(t *testing.T) {
+	steps := []string{
+		"grant token",
+		"grant token rejected",
+		"grant token malformed",
+		"websocket dial",
+		"websocket rejected",
+		"websocket read",
+		"websocket malformed",
+	}
+
+	for _, step := range steps {
+		t.Run(step, func(t *testing.T) {
+			down := newOfferBroker(t, true)
+			failing := newFailingBroker(t, step)
+			up := newOfferBroker(t, false)
+
+			launcherConfig := &config.LauncherConfig{
+				BaseConfig: &config.BaseConfig{
+					AuthToken:      "auth-token",
+					Brokers:        []config.Broker{{URI: down.URL}, {URI: failing.URL}, {URI: up.URL}},
+					CrashThreshold: 5,
+				},
+				RunnerConfigs: map[string]*config.RunnerConfig{
+					"javascript": {RunnerType: "javascript"},
+				},
+			}
+
+			pool, err := broker.NewPool(launcherConfig.BaseConfig)
+			require.NoError(t, err)
+
+			logger := logs.NewLogger(logs.ErrorLevel, "")
+			registry := status.NewRegistry()
+			cmd := NewLaunchCommand(logger, registry, newGate())
+			cmd.pool = pool
+			crashes := newCrashLoop("javascript", 5, time.Minute, registry, logger)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			endpoint := cmd.awaitOffer(ctx, launcherConfig, "javascript", 0, crashes)
+			require.NotNil(t, endpoint, "Expected offer to be accepted")
+
+			assert.Equal(t, up.URL, endpoint.URI, "Expected failover past the task broker that failed")
+		})
+	}
+}
+
+func TestFetchRunnerGrantTokenFailsOver(t *testing.T) {
+	var attempts atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+	up := newOfferBroker(t, false)
+
+	baseConfig := &config.BaseConfig{
+		AuthToken: "auth-token",
+		Brokers:   []config.Broker{{URI: failing.URL}, {URI: up.URL}},
+	}
+	pool, err := broker.NewPool(baseConfig)
+	require.NoError(t, err)
+
+	cmd := NewLaunchCommand(logs.NewLogger(logs.ErrorLevel, ""), status.NewRegistry(), newGate())
+
+	start := time.Now()
+	endpoint, token := cmd.fetchRunnerGrantToken(context.Background(), baseConfig.AuthToken, pool.Endpoints())
+	require.NotNil(t, endpoint, "Expected a task broker to grant a token")
+
+	assert.Equal(t, up.URL, endpoint.URI, "Expected failover past the task broker that returned 500")
+	assert.Equal(t, "grant-token", token)
+	assert.Equal(t, int32(1), attempts.Load(), "Expected a single attempt at the failing task broker")
+	assert.Less(t, time.Since(start), time.Second, "Expected failover without retrying the failing task broker")
+
+	endpoint, _ = cmd.fetchRunnerGrantToken(context.Background(), baseConfig.AuthToken, pool.Endpoints()[:1])
+	assert.Nil(t, endpoint, "Expected no task broker when none grants a token")
+}
+
+func TestSuperviseRunnerDrainsOnMaxLifetime(t *testing.T) {
+	tests := []struct {
+		name string
+		// script of the runner, looping until it exits
+		script       string
+		expectKilled bool
+	}{
+		{
+			name:   "runner exits on its own once drained",
+			script: "trap 'sleep 0.2; exit 0' TERM; while :; do sleep 0.05; done",
+		},
+		{
+			name:         "runner still draining at max launch duration is killed",
+			script:       "trap '' TERM; while :; do sleep 0.05; done",
+			expectKilled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := process.Start(process.Command("/bin/sh", "-c", tt.script), io.Discard, io.Discard)
+			require.NoError(t, err)
+
+			exited := make(chan struct{})
+			var waitErr error
+			go func() {
+				waitErr = runner.Wait()
+				close(exited)
+			}()
+
+			cmd := NewLaunchCommand(logs.NewLogger(logs.ErrorLevel, ""), status.NewRegistry(), newGate())
+			runnerConfig := &config.RunnerConfig{MaxLifetime: 1, MaxLaunchDuration: 2}
+			recycled := make(chan struct{})
+			healthMonitorStopped := false
+			start := time.Now()
+
+			go cmd.superviseRunner(context.Background(), runner, runnerConfig, time.Second, exited, recycled, func() {
+				healthMonitorStopped = true
+			})
+
+			select {
+			case <-recycled:
+			case <-time.After(3 * time.Second):
+				t.Fatal("Expected runner to be recycled on reaching its max lifetime")
+			}
+			assert.True(t, healthMonitorStopped)
+
+			select {
+			case <-exited:
+			case <-time.After(3 * time.Second):
+				t.Fatal("Expected draining runner to have exited by its max launch duration")
+			}
+			assert.Equal(t, string(status.KillReasonRecycled), runner.TerminateReason())
+
+			if tt.expectKilled {
+				assert.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+				var exitErr *exec.ExitError
+				require.ErrorAs(t, waitErr, &exitErr)
+				assert.Equal(t, syscall.SIGKILL, exitErr.Sys().(syscall.WaitStatus).Signal())
+			} else {
+				assert.Less(t, time.Since(start), 2*time.Second, "Expected runner to exit on the drain signal, before its max launch duration")
+				assert.NoError(t, waitErr, "Expected runner to exit on its own")
+			}
+		})
+	}
+}
+
+func TestLaunchOnDemandPreparesLaunchBeforeOffer(t *testing.T) {
+	var offers atomic.Int32
+	offerBroker := newOfferBroker(t, false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/runners/_ws" {
+			offers.Add(1)
+		}
+		offerBroker.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name          string
+		runnerConfig  config.RunnerConfig
+		expectedError string
+	}{
+		{
+			name: "failing pre-launch hook",
+			runnerConfig: config.RunnerConfig{
+				PreLaunch: &config.HookConfig{Command: "/bin/sh", Args: []string{"-c", "exit 1"}},
+			},
+		},
+		{
+			name:          "failing integrity check",
+			runnerConfig:  config.RunnerConfig{CommandSHA256: strings.Repeat("0", 64)},
+			expectedError: "command: sha256 of /bin/true is",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offers.Store(0)
+
+			runnerConfig := tt.runnerConfig
+			runnerConfig.RunnerType = "javascript"
+			runnerConfig.WorkDir = t.TempDir()
+			runnerConfig.Command = "/bin/true"
+
+			launcherConfig := &config.LauncherConfig{
+				BaseConfig: &config.BaseConfig{
+					AuthToken:      "auth-token",
+					TaskBrokerURI:  srv.URL,
+					CrashThreshold: 5,
+				},
+				RunnerConfigs: map[string]*config.RunnerConfig{"javascript": &runnerConfig},
+			}
+
+			pool, err := broker.NewPool(launcherConfig.BaseConfig)
+			require.NoError(t, err)
+
+			logger := logs.NewLogger(logs.ErrorLevel, "")
+			registry := status.NewRegistry()
+			cmd := NewLaunchCommand(logger, registry, newGate())
+			cmd.pool = pool
+			crashes := newCrashLoop("javascript", 5, time.Minute, registry, logger)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			g := newGroup(ctx)
+
+			err = cmd.launchOnDemand(ctx, launcherConfig, "javascript", nil, newSlots(0, 1), []ports{newPorts([]string{"5681"})}, crashes, g)
+			require.NoError(t, err)
+			require.NoError(t, g.Wait())
+
+			assert.Zero(t, offers.Load(), "Expected no task offer for an aborted launch")
+			runner := registry.Runners()["javascript"]
+			assert.Equal(t, 1, runner.ConsecutiveFailures)
+			assert.Contains(t, runner.IntegrityError, tt.expectedError)
+		})
+	}
+}
+
+// newTaskBroker starts a task broker that is ready, hands out grant tokens and
+// accepts up to the given number of task offers, holding any further offer
+// pending until the launcher withdraws it. Returns the task broker and the
+// number of offers accepted so far.
+func newTaskBroker(t *testing.T, maxAccepted int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var accepted atomic.Int32
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST /runners/auth", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"token": "grant-token"}}`))
+	})
+	mux.HandleFunc("GET /runners/_ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg map[string]any
+		_ = conn.WriteJSON(map[string]string{"type": "broker:inforequest"})
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		_ = conn.WriteJSON(map[string]string{"type": "broker:runnerregistered"})
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if accepted.Add(1) > maxAccepted {
+			accepted.Add(-1)
+			_, _, _ = conn.ReadMessage() // pending until withdrawn
+			return
+		}
+		_ = conn.WriteJSON(map[string]string{"type": "broker:taskofferaccept", "taskId": "task-id"})
+		_ = conn.ReadJSON(&msg)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv, &accepted
+}
+
+// fakeRunnerScript is a runner that appends its start, with its PID, health
+// check port and auto-shutdown timeout, and its exit to the log at its first
+// arg. It exits after the number of seconds at its second arg, if any, else
+// once a file named after the log and its PID exists, or on SIGTERM.
+const fakeRunnerScript = `log="$1"
+echo "start $$ $N8N_RUNNERS_HEALTH_CHECK_SERVER_PORT $N8N_RUNNERS_AUTO_SHUTDOWN_TIMEOUT" >> "$log"
+trap 'echo "exit $$" >> "$log"; exit 0' TERM
+if [ -n "$2" ]; then
+	sleep "$2"
+else
+	while [ ! -e "$log.$$" ]; do sleep 0.02; done
+fi
+echo "exit $$" >> "$log"`
+
+// runnerEvent is a start or exit of a fake runner, as logged by it.
+type runnerEvent struct {
+	kind         string
+	pid          string
+	port         string
+	autoShutdown string
+}
+
+// readRunnerLog reads the events logged by fake runners, in order.
+func readRunnerLog(t *testing.T, path string) []runnerEvent {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var events []runnerEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := append(strings.Fields(line), "", "", "")
+		events = append(events, runnerEvent{kind: fields[0], pid: fields[1], port: fields[2], autoShutdown: fields[3]})
+	}
+
+	return events
+}
+
+// countRunnerEvents counts the logged events of the given kind.
+func countRunnerEvents(events []runnerEvent, kind string) int {
+	count := 0
+	for _, event := range events {
+		if event.kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// executeLaunch runs the launch cycle for the runner type with the fake runner,
+// attached to the given task broker, until the test ends. Returns the log of
+// the fake runners and the registry of the launch cycle.
+func executeLaunch(t *testing.T, brokerURI string, runnerConfig config.RunnerConfig, runnerArgs ...string) (string, *status.Registry) {
+	t.Helper()
+
+	runnerLog := filepath.Join(t.TempDir(), "runners.log")
+	runnerConfig.RunnerType = "javascript"
+	runnerConfig.WorkDir = t.TempDir()
+	runnerConfig.Command = "/bin/sh"
+	runnerConfig.Args = append([]string{"-c", fakeRunnerScript, "runner", runnerLog}, runnerArgs...)
+	runnerConfig.HealthCheckServerPort = "5681"
+
+	launcherConfig := &config.LauncherConfig{
+		BaseConfig: &config.BaseConfig{
+			LogLevel:                    "error",
+			AuthToken:                   "auth-token",
+			AutoShutdownTimeout:         "15",
+			Brokers:                     []config.Broker{{URI: brokerURI}},
+			GracePeriod:                 1,
+			CrashWindow:                 10,
+			CrashThreshold:              5,
+			CrashCooldown:               60,
+			RunnerHealthCheckServerHost: "127.0.0.1",
+		},
+		RunnerConfigs: map[string]*config.RunnerConfig{"javascript": &runnerConfig},
+	}
+
+	registry := status.NewRegistry()
+	cmd := NewLaunchCommand(logs.NewLogger(logs.ErrorLevel, ""), registry, newGate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Execute(ctx, launcherConfig, "javascript")
+	}()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Error("Expected launch cycle to end on cancellation")
+		}
+	})
+
+	return runnerLog, registry
+}
+
+func TestExecuteKeepsWarmPool(t *testing.T) {
+	taskBroker, accepted := newTaskBroker(t, 0)
+	runnerLog, registry := executeLaunch(t, taskBroker.URL, config.RunnerConfig{MinIdle: 1, MaxConcurrency: 2})
+
+	// 1. warm runner starts without any task offer being accepted
+
+	var events []runnerEvent
+	require.Eventually(t, func() bool {
+		events = readRunnerLog(t, runnerLog)
+		return countRunnerEvents(events, "start") == 1
+	}, 5*time.Second, 20*time.Millisecond, "Expected warm runner to start")
+
+	warm := events[0]
+	assert.Zero(t, accepted.Load(), "Expected warm runner to start before any task offer is accepted")
+	assert.Equal(t, "0", warm.autoShutdown, "Expected warm runner not to shut down on idle timeout")
+	assert.Equal(t, "5681", warm.port)
+	assert.Eventually(t, func() bool {
+		instance := registry.Runners()["javascript"].Instances[0]
+		return instance.State == status.StateRunning && strconv.Itoa(instance.PID) == warm.pid
+	}, time.Second, 10*time.Millisecond, "Expected warm runner to run as the first instance")
+
+	// 2. pool is refilled once the warm runner exits
+
+	require.NoError(t, os.WriteFile(runnerLog+"."+warm.pid, nil, 0o600))
+
+	require.Eventually(t, func() bool {
+		events = readRunnerLog(t, runnerLog)
+		return countRunnerEvents(events, "start") == 2
+	}, 5*time.Second, 20*time.Millisecond, "Expected warm runner to be replaced")
+
+	assert.Equal(t, []runnerEvent{{kind: "exit", pid: warm.pid}}, events[1:2], "Expected replacement only once the warm runner exited")
+	replacement := events[2]
+	assert.NotEqual(t, warm.pid, replacement.pid)
+	assert.Equal(t, "0", replacement.autoShutdown)
+	assert.Zero(t, accepted.Load(), "Expected pool to be refilled without any task offer being accepted")
+}
+
+func TestExecuteLimitsConcurrency(t *testing.T) {
+	const maxConcurrency, offers = 2, 6
+
+	taskBroker, accepted := newTaskBroker(t, offers)
+	runnerLog, _ := executeLaunch(t, taskBroker.URL, config.RunnerConfig{MaxConcurrency: maxConcurrency}, "0.3")
+
+	var events []runnerEvent
+	require.Eventually(t, func() bool {
+		events = readRunnerLog(t, runnerLog)
+		return countRunnerEvents(events, "exit") == offers
+	}, 10*time.Second, 20*time.Millisecond, "Expected a runner for every accepted offer, slots being released on exit")
+	assert.Equal(t, int32(offers), accepted.Load())
+	assert.Equal(t, offers, countRunnerEvents(events, "start"))
+
+	running := map[string]string{} // PID to health check port
+	ports := map[string]bool{}
+	peak := 0
+	for _, event := range events {
+		switch event.kind {
+		case "start":
+			for pid, port := range running {
+				assert.NotEqual(t, port, event.port, "Expected runner %s to use a port other than running runner %s", event.pid, pid)
+			}
+			running[event.pid] = event.port
+			ports[event.port] = true
+			peak = max(peak, len(running))
+		case "exit":
+			delete(running, event.pid)
+		}
+	}
+
+	assert.Equal(t, maxConcurrency, peak, "Expected up to max-concurrency runners at once")
+	assert.Equal(t, map[string]bool{"5681": true, "5682": true}, ports, "Expected a health check port per instance")
+}
@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
+)
+
+var (
+	// errConfigReloaded is the cause of stopping a runner type's launch cycle on
+	// a config reload that changed the runner type's config.
+	errConfigReloaded = errors.New("runner config changed on reload")
+
+	// errRestarted is the cause of stopping a runner type's launch cycle on a
+	// restart via the admin API.
+	errRestarted = errors.New("runner restarted")
+
+	// errDrained is the cause of stopping a runner type's launch cycle on a
+	// drain via the admin API.
+	errDrained = errors.New("runner drained")
+)
+
+// Supervisor runs the launch cycle of each runner type, applies reloaded configs
+// by relaunching the launch cycles of runner types whose config changed, and
+// restarts, drains, pauses and resumes runner types on request.
+type Supervisor struct {
+	ctx      context.Context
+	registry *status.Registry
+
+	// gates of runner types, fixed on creation
+	gates map[string]*gate
+
+	mu             sync.Mutex
+	launcherConfig *config.LauncherConfig
+	cycles         map[string]*cycle
+
+	// number of launch cycles running or about to be relaunched, and a channel
+	// closed once it drops to zero
+	runningMu sync.Mutex
+	running   int
+	stopped   chan struct{}
+
+	hasFailed atomic.Bool
+}
+
+// cycle is the launch cycle of a runner type, stoppable on its own.
+type cycle struct {
+	stop context.CancelCauseFunc
+	done chan struct{}
+}
+
+// NewSupervisor creates a supervisor whose launch cycles run until the context
+// is cancelled.
+func NewSupervisor(ctx context.Context, launcherConfig *config.LauncherConfig, registry *status.Registry) *Supervisor {
+	gates := make(map[string]*gate, len(launcherConfig.RunnerConfigs))
+	for runnerType := range launcherConfig.RunnerConfigs {
+		gates[runnerType] = newGate()
+	}
+
+	return &Supervisor{
+		ctx:            ctx,
+		registry:       registry,
+		gates:          gates,
+		launcherConfig: launcherConfig,
+		cycles:         make(map[string]*cycle),
+		stopped:        make(chan struct{}),
+	}
+}
+
+// Start starts the launch cycle of each given runner type.
+func (s *Supervisor) Start(runnerTypes []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, runnerType := range runnerTypes {
+		s.start(runnerType)
+	}
+}
+
+// Reload applies the given config, which must be valid: the launch cycle of each
+// runner type whose config changed is stopped, terminating its runners, and
+// started again with the new config once all its runners have exited. Runner
+// types whose config is unchanged keep running.
+func (s *Supervisor) Reload(launcherConfig *config.LauncherConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	for runnerType := range s.cycles {
+		if reflect.DeepEqual(s.launcherConfig.RunnerConfigs[runnerType], launcherConfig.RunnerConfigs[runnerType]) {
+			continue
+		}
+		logs.Infof("Config of runner %s changed, relaunching runner...", runnerType)
+		changed = append(changed, runnerType)
+	}
+
+	if len(changed) == 0 {
+		logs.Info("Reloaded config, no runner config changed")
+		return
+	}
+
+	if s.relaunch(changed, errConfigReloaded, launcherConfig) {
+		logs.Infof("Reloaded config, relaunched %d runner(s)", len(changed))
+	}
+}
+
+// Restart stops the launch cycle of the runner type in the background,
+// terminating its runners, and starts it again once all its runners have
+// exited, e.g. to recover a runner type whose launch cycle failed.
+func (s *Supervisor) Restart(runnerType string) error {
+	if _, ok := s.gates[runnerType]; !ok {
+		return errs.ErrUnknownRunnerType
+	}
+
+	logs.Infof("Restarting runner %s...", runnerType)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.relaunch([]string{runnerType}, errRestarted, s.launcherConfig)
+	}()
+
+	return nil
+}
+
+// Drain pauses the runner type and terminates its runners in the background,
+// letting each runner finish its current task within the grace period.
+func (s *Supervisor) Drain(runnerType string) error {
+	if err := s.Pause(runnerType); err != nil {
+		return err
+	}
+
+	logs.Infof("Draining runner %s...", runnerType)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.relaunch([]string{runnerType}, errDrained, s.launcherConfig)
+	}()
+
+	return nil
+}
+
+// Pause stops launching runners of the runner type and withdraws any pending
+// task offer for it, while running runners keep running.
+func (s *Supervisor) Pause(runnerType string) error {
+	gate, ok := s.gates[runnerType]
+	if !ok {
+		return errs.ErrUnknownRunnerType
+	}
+
+	if gate.pause() {
+		logs.Infof("Paused runner %s", runnerType)
+		s.registry.Update(runnerType, func(runner *status.Runner) {
+			runner.Paused = true
+		})
+	}
+
+	return nil
+}
+
+// Resume resumes launching runners of a paused or drained runner type.
+func (s *Supervisor) Resume(runnerType string) error {
+	gate, ok := s.gates[runnerType]
+	if !ok {
+		return errs.ErrUnknownRunnerType
+	}
+
+	if gate.resume() {
+		logs.Infof("Resumed runner %s", runnerType)
+		s.registry.Update(runnerType, func(runner *status.Runner) {
+			runner.Paused = false
+		})
+	}
+
+	return nil
+}
+
+// relaunch stops the launch cycles of the given runner types with the given
+// cause, terminating their runners, and once all their runners have exited,
+// starts them again with the given config. Returns false if the launcher is
+// shutting down. To be called with the mutex held.
+func (s *Supervisor) relaunch(runnerTypes []string, cause error, launcherConfig *config.LauncherConfig) bool {
+	// keep Wait from returning while launch cycles are being relaunched, unless
+	// all launch cycles have already stopped
+	if !s.hold() {
+		return false
+	}
+	defer s.release()
+
+	for _, runnerType := range runnerTypes {
+		s.cycles[runnerType].stop(cause)
+	}
+
+	// wait for all launch cycles to stop before starting any, as new configs
+	// may reuse ports that the old runners are holding
+	for _, runnerType := range runnerTypes {
+		<-s.cycles[runnerType].done
+	}
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+
+	s.launcherConfig = launcherConfig
+	for _, runnerType := range runnerTypes {
+		s.start(runnerType)
+	}
+
+	return true
+}
+
+// Wait blocks until the launch cycles of all runner types have stopped, and
+// returns whether any launch cycle failed.
+func (s *Supervisor) Wait() bool {
+	<-s.stopped
+
+	return s.hasFailed.Load()
+}
+
+// hold counts one more running launch cycle, unless all have stopped, in which
+// case it returns false.
+func (s *Supervisor) hold() bool {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if s.running == 0 {
+		return false
+	}
+	s.running++
+
+	return true
+}
+
+// release counts one fewer running launch cycle.
+func (s *Supervisor) release() {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	s.running--
+	if s.running == 0 {
+		close(s.stopped)
+	}
+}
+
+func (s *Supervisor) start(runnerType string) {
+	launcherConfig := s.launcherConfig
+	ctx, stop := context.WithCancelCause(s.ctx)
+	c := &cycle{stop: stop, done: make(chan struct{})}
+	s.cycles[runnerType] = c
+
+	s.runningMu.Lock()
+	s.running++
+	s.runningMu.Unlock()
+
+	go func() {
+		defer s.release()
+		defer close(c.done)
+		defer stop(nil)
+
+		logLevel := logs.ParseLevel(launcherConfig.BaseConfig.LogLevel)
+		logPrefix := logs.GetLauncherPrefix(runnerType)
+		logger := logs.NewLogger(logLevel, logPrefix)
+
+		cmd := NewLaunchCommand(logger, s.registry, s.gates[runnerType])
+		if err := cmd.Execute(ctx, launcherConfig, runnerType); err != nil {
+			logger.Errorf("Failed to execute `launch` command: %v", err)
+			s.hasFailed.Store(true)
+		}
+	}()
+}
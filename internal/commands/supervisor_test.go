@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/status"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSupervisorTestConfig(workDirs map[string]string) *config.LauncherConfig {
+	launcherConfig := &config.LauncherConfig{
+		BaseConfig: &config.BaseConfig{
+			LogLevel:       "error",
+			TaskBrokerURI:  "http://127.0.0.1:1", // never ready, so launch cycles run until stopped
+			CrashThreshold: 5,
+		},
+		RunnerConfigs: make(map[string]*config.RunnerConfig),
+	}
+
+	for i, runnerType := range slices.Sorted(maps.Keys(workDirs)) {
+		launcherConfig.RunnerConfigs[runnerType] = &config.RunnerConfig{
+			RunnerType:            runnerType,
+			WorkDir:               workDirs[runnerType],
+			Command:               "true",
+			HealthCheckServerPort: strconv.Itoa(5681 + i),
+		}
+	}
+
+	return launcherConfig
+}
+
+func TestSupervisorReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	launcherConfig := newSupervisorTestConfig(map[string]string{"javascript": dir, "python": dir})
+	supervisor := NewSupervisor(ctx, launcherConfig, status.NewRegistry())
+	supervisor.Start([]string{"javascript", "python"})
+
+	javascript := supervisor.cycles["javascript"]
+	python := supervisor.cycles["python"]
+
+	reloaded := newSupervisorTestConfig(map[string]string{"javascript": dir, "python": dir})
+	reloaded.RunnerConfigs["python"].Args = []string{"--changed"}
+	supervisor.Reload(reloaded)
+
+	assert.Same(t, javascript, supervisor.cycles["javascript"], "unchanged launch cycle should keep running")
+	assert.NotSame(t, python, supervisor.cycles["python"], "changed launch cycle should be relaunched")
+	assert.Equal(t, []string{"--changed"}, supervisor.launcherConfig.RunnerConfigs["python"].Args)
+
+	select {
+	case <-python.done:
+	default:
+		t.Fatal("Expected changed launch cycle to have stopped")
+	}
+
+	select {
+	case <-javascript.done:
+		t.Fatal("Expected unchanged launch cycle to keep running")
+	default:
+	}
+
+	cancel()
+
+	assert.False(t, supervisor.Wait())
+}
+
+func TestSupervisorWaitOnFailure(t *testing.T) {
+	launcherConfig := newSupervisorTestConfig(map[string]string{"javascript": filepath.Join(t.TempDir(), "missing")})
+	supervisor := NewSupervisor(context.Background(), launcherConfig, status.NewRegistry())
+	supervisor.Start([]string{"javascript"})
+
+	stopped := make(chan bool)
+	go func() { stopped <- supervisor.Wait() }()
+
+	select {
+	case hasFailed := <-stopped:
+		assert.True(t, hasFailed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Wait to return once the failed launch cycle has stopped")
+	}
+
+	// reloading once all launch cycles have stopped is a no-op
+	reloaded := newSupervisorTestConfig(map[string]string{"javascript": t.TempDir()})
+	supervisor.Reload(reloaded)
+	require.Len(t, supervisor.cycles, 1)
+	assert.NotSame(t, reloaded, supervisor.launcherConfig)
+}
+
+func TestSupervisorPauseResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registry := status.NewRegistry()
+	supervisor := NewSupervisor(ctx, newSupervisorTestConfig(map[string]string{"javascript": t.TempDir()}), registry)
+	supervisor.Start([]string{"javascript"})
+
+	require.NoError(t, supervisor.Pause("javascript"))
+	assert.True(t, registry.Runners()["javascript"].Paused)
+	assert.Equal(t, status.StatePaused, registry.Runners()["javascript"].State)
+
+	require.NoError(t, supervisor.Resume("javascript"))
+	assert.False(t, registry.Runners()["javascript"].Paused)
+
+	assert.ErrorIs(t, supervisor.Pause("ruby"), errs.ErrUnknownRunnerType)
+	assert.ErrorIs(t, supervisor.Resume("ruby"), errs.ErrUnknownRunnerType)
+	assert.ErrorIs(t, supervisor.Drain("ruby"), errs.ErrUnknownRunnerType)
+	assert.ErrorIs(t, supervisor.Restart("ruby"), errs.ErrUnknownRunnerType)
+
+	cancel()
+
+	assert.False(t, supervisor.Wait())
+}
+
+func TestSupervisorRestartAndDrain(t *testing.T) {
+	tests := []struct {
+		name           string
+		action         func(*Supervisor, string) error
+		expectedPaused bool
+	}{
+		{name: "restart", action: (*Supervisor).Restart},
+		{name: "drain", action: (*Supervisor).Drain, expectedPaused: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			registry := status.NewRegistry()
+			supervisor := NewSupervisor(ctx, newSupervisorTestConfig(map[string]string{"javascript": t.TempDir()}), registry)
+			supervisor.Start([]string{"javascript"})
+
+			supervisor.mu.Lock()
+			before := supervisor.cycles["javascript"]
+			supervisor.mu.Unlock()
+
+			require.NoError(t, tt.action(supervisor, "javascript"))
+
+			assert.Eventually(t, func() bool {
+				supervisor.mu.Lock()
+				defer supervisor.mu.Unlock()
+				return supervisor.cycles["javascript"] != before
+			}, 5*time.Second, 10*time.Millisecond, "launch cycle should be relaunched")
+
+			<-before.done
+			assert.Equal(t, tt.expectedPaused, registry.Runners()["javascript"].Paused)
+
+			cancel()
+
+			assert.False(t, supervisor.Wait())
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Broker is a task broker that the launcher may connect to.
+type Broker struct {
+	URI string
+
+	// Weight of the task broker among weighted task brokers, or 0 if the task
+	// brokers are ordered by priority instead.
+	Weight int
+}
+
+// TaskBrokers returns the task brokers to fail over between, i.e. those listed
+// in TaskBrokerURIs or else the single task broker at TaskBrokerURI.
+func (c *BaseConfig) TaskBrokers() []Broker {
+	if len(c.Brokers) > 0 {
+		return c.Brokers
+	}
+
+	return []Broker{{URI: c.TaskBrokerURI}}
+}
+
+// RunnerBaseConfig returns the base config for the given runner type, i.e. the
+// base config with the runner's `task-broker-uri` and `auth-token-file`, if set,
+// in place of the launcher's task brokers and auth token.
+func (c *LauncherConfig) RunnerBaseConfig(runnerType string) *BaseConfig {
+	runnerConfig, ok := c.RunnerConfigs[runnerType]
+	if !ok || (runnerConfig.TaskBrokerURI == "" && runnerConfig.AuthToken == "") {
+		return c.BaseConfig
+	}
+
+	baseConfig := *c.BaseConfig
+	if runnerConfig.TaskBrokerURI != "" {
+		baseConfig.TaskBrokerURI = runnerConfig.TaskBrokerURI
+		baseConfig.Brokers = nil
+	}
+	if runnerConfig.AuthToken != "" {
+		baseConfig.AuthToken = runnerConfig.AuthToken
+	}
+
+	return &baseConfig
+}
+
+// resolveAuthToken reads the runner's auth token from `auth-token-file`, if set.
+func (c *RunnerConfig) resolveAuthToken() error {
+	if c.AuthTokenFile == "" {
+		return nil
+	}
+
+	// #nosec G304 -- auth-token-file is controlled by system administrator via config file
+	content, err := os.ReadFile(c.AuthTokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read auth-token-file: %w", err)
+	}
+
+	c.AuthToken = strings.TrimRight(string(content), "\n\r")
+	if c.AuthToken == "" {
+		return fmt.Errorf("auth-token-file at %s must not be empty", c.AuthTokenFile)
+	}
+
+	return nil
+}
+
+// parseBrokers parses a comma-separated list of task broker URIs, in order of
+// priority, e.g. `http://worker-1:5679,http://worker-2:5679`, or with a weight
+// for every URI, e.g. `http://worker-1:5679;weight=3,http://worker-2:5679;weight=1`.
+func parseBrokers(list string, listName string) ([]Broker, error) {
+	var brokers []Broker
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		uri, params, hasParams := strings.Cut(entry, ";")
+		broker := Broker{URI: uri}
+
+		if hasParams {
+			weight, ok := strings.CutPrefix(params, "weight=")
+			n, err := strconv.Atoi(weight)
+			if !ok || err != nil || n < 1 {
+				errs = append(errs, fmt.Errorf("%s entry %s must be followed by `;weight=N` with N >= 1, if by anything", listName, uri))
+				continue
+			}
+			broker.Weight = n
+		}
+
+		if err := validateTaskBrokerURI(uri, listName+" entry "+uri); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if seen[uri] {
+			errs = append(errs, fmt.Errorf("%s must not contain %s more than once", listName, uri))
+			continue
+		}
+		seen[uri] = true
+
+		brokers = append(brokers, broker)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("%s must contain at least one task broker URI", listName)
+	}
+
+	weighted := 0
+	for _, broker := range brokers {
+		if broker.Weight > 0 {
+			weighted++
+		}
+	}
+	if weighted > 0 && weighted < len(brokers) {
+		return nil, fmt.Errorf("%s must have a weight for all task broker URIs or for none", listName)
+	}
+
+	return brokers, nil
+}
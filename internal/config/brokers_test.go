@@ -0,0 +1,199 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sethvargo/go-envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBrokers(t *testing.T) {
+	tests := []struct {
+		name          string
+		list          string
+		expected      []Broker
+		expectedError string
+	}{
+		{
+			name: "ordered",
+			list: "http://worker-1:5679, http://worker-2:5679",
+			expected: []Broker{
+				{URI: "http://worker-1:5679"},
+				{URI: "http://worker-2:5679"},
+			},
+		},
+		{
+			name: "weighted",
+			list: "http://worker-1:5679;weight=3,unix:///run/n8n/broker.sock;weight=1",
+			expected: []Broker{
+				{URI: "http://worker-1:5679", Weight: 3},
+				{URI: "unix:///run/n8n/broker.sock", Weight: 1},
+			},
+		},
+		{
+			name:          "empty",
+			list:          " , ",
+			expectedError: "N8N_RUNNERS_TASK_BROKER_URIS must contain at least one task broker URI",
+		},
+		{
+			name:          "invalid URI",
+			list:          "http://worker-1:5679,worker-2:5679",
+			expectedError: "N8N_RUNNERS_TASK_BROKER_URIS entry worker-2:5679 must use http:// or https:// scheme",
+		},
+		{
+			name:          "invalid weight",
+			list:          "http://worker-1:5679;weight=0,http://worker-2:5679;weight=1",
+			expectedError: "N8N_RUNNERS_TASK_BROKER_URIS entry http://worker-1:5679 must be followed by `;weight=N` with N >= 1, if by anything",
+		},
+		{
+			name:          "unknown param",
+			list:          "http://worker-1:5679;priority=1",
+			expectedError: "must be followed by `;weight=N` with N >= 1, if by anything",
+		},
+		{
+			name:          "partial weights",
+			list:          "http://worker-1:5679;weight=2,http://worker-2:5679",
+			expectedError: "N8N_RUNNERS_TASK_BROKER_URIS must have a weight for all task broker URIs or for none",
+		},
+		{
+			name:          "duplicate URI",
+			list:          "http://worker-1:5679,http://worker-1:5679",
+			expectedError: "N8N_RUNNERS_TASK_BROKER_URIS must not contain http://worker-1:5679 more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			brokers, err := parseBrokers(tt.list, "N8N_RUNNERS_TASK_BROKER_URIS")
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, brokers)
+		})
+	}
+}
+
+func TestLoadBrokers(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{"task-runners": [{"runner-type": "javascript", "workdir": "/", "command": "node"}]}`
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+
+	tests := []struct {
+		name     string
+		envVars  map[string]string
+		expected []Broker
+	}{
+		{
+			name:     "default task broker",
+			envVars:  map[string]string{},
+			expected: []Broker{{URI: "http://127.0.0.1:5679"}},
+		},
+		{
+			name:     "single task broker",
+			envVars:  map[string]string{"N8N_RUNNERS_TASK_BROKER_URI": "http://worker-1:5679"},
+			expected: []Broker{{URI: "http://worker-1:5679"}},
+		},
+		{
+			name: "list of task brokers takes precedence",
+			envVars: map[string]string{
+				"N8N_RUNNERS_TASK_BROKER_URI":  "http://worker-1:5679",
+				"N8N_RUNNERS_TASK_BROKER_URIS": "http://worker-2:5679,http://worker-3:5679",
+			},
+			expected: []Broker{{URI: "http://worker-2:5679"}, {URI: "http://worker-3:5679"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.envVars["N8N_RUNNERS_AUTH_TOKEN"] = "test-token"
+			tt.envVars["N8N_RUNNERS_CONFIG_PATH"] = configPath
+
+			cfg, err := LoadLauncherConfig([]string{"javascript"}, envconfig.MapLookuper(tt.envVars))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, cfg.BaseConfig.TaskBrokers())
+		})
+	}
+}
+
+func TestRunnerBaseConfig(t *testing.T) {
+	dir := t.TempDir()
+	tokenPath := filepath.Join(dir, "python-token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("python-token\n"), 0o600))
+	emptyTokenPath := filepath.Join(dir, "empty-token")
+	require.NoError(t, os.WriteFile(emptyTokenPath, nil, 0o600))
+
+	tests := []struct {
+		name              string
+		override          string
+		expectedBrokers   []Broker
+		expectedAuthToken string
+		expectedError     string
+	}{
+		{
+			name:              "no override",
+			override:          `"runner-type": "python"`,
+			expectedBrokers:   []Broker{{URI: "http://worker-1:5679"}, {URI: "http://worker-2:5679"}},
+			expectedAuthToken: "test-token",
+		},
+		{
+			name:              "task broker override",
+			override:          `"runner-type": "python", "task-broker-uri": "http://python-worker:5679"`,
+			expectedBrokers:   []Broker{{URI: "http://python-worker:5679"}},
+			expectedAuthToken: "test-token",
+		},
+		{
+			name:              "auth token override",
+			override:          `"runner-type": "python", "auth-token-file": "` + tokenPath + `"`,
+			expectedBrokers:   []Broker{{URI: "http://worker-1:5679"}, {URI: "http://worker-2:5679"}},
+			expectedAuthToken: "python-token",
+		},
+		{
+			name:          "invalid task broker URI",
+			override:      `"runner-type": "python", "task-broker-uri": "python-worker:5679"`,
+			expectedError: "runner python: task-broker-uri must use http:// or https:// scheme",
+		},
+		{
+			name:          "missing auth token file",
+			override:      `"runner-type": "python", "auth-token-file": "` + filepath.Join(dir, "missing") + `"`,
+			expectedError: "runner python: failed to read auth-token-file",
+		},
+		{
+			name:          "empty auth token file",
+			override:      `"runner-type": "python", "auth-token-file": "` + emptyTokenPath + `"`,
+			expectedError: "runner python: auth-token-file at " + emptyTokenPath + " must not be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			content := `{"task-runners": [{` + tt.override + `, "workdir": "/", "command": "python", "health-check-server-port": "5682"}]}`
+			require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+
+			cfg, err := LoadLauncherConfig([]string{"python"}, envconfig.MapLookuper(map[string]string{
+				"N8N_RUNNERS_AUTH_TOKEN":       "test-token",
+				"N8N_RUNNERS_TASK_BROKER_URIS": "http://worker-1:5679,http://worker-2:5679",
+				"N8N_RUNNERS_CONFIG_PATH":      configPath,
+			}))
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			baseConfig := cfg.RunnerBaseConfig("python")
+			assert.Equal(t, tt.expectedBrokers, baseConfig.TaskBrokers())
+			assert.Equal(t, tt.expectedAuthToken, baseConfig.AuthToken)
+			assert.Equal(t, "test-token", cfg.BaseConfig.AuthToken, "base config must be left unchanged")
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CgroupConfig holds the cgroup v2 resource limits applied to each launch of
+// a runner. Unset fields leave the corresponding limit at its default.
+type CgroupConfig struct {
+	// Hard limit on memory usage, in bytes or with a K, M or G suffix, e.g. `512M`.
+	// On reaching it, the kernel OOM-kills the runner.
+	MemoryMax string `json:"memory-max,omitempty"`
+
+	// Soft limit on memory usage, in bytes or with a K, M or G suffix, e.g. `384M`.
+	// On exceeding it, the runner is throttled and its memory reclaimed.
+	MemoryHigh string `json:"memory-high,omitempty"`
+
+	// Relative share of CPU time, from 1 to 10000. Default: 100.
+	CPUWeight int `json:"cpu-weight,omitempty"`
+
+	// Max CPU time as a number of CPUs, e.g. `0.5` for half a CPU.
+	CPUQuota float64 `json:"cpu-quota,omitempty"`
+
+	// Max number of processes and threads in the runner's process tree.
+	PidsMax int `json:"pids-max,omitempty"`
+}
+
+// ParseByteSize parses a size in bytes, optionally with a K, M or G suffix for
+// binary multiples, e.g. `512M`. The special value `max` means no limit and is
+// returned as -1.
+func ParseByteSize(size string) (int64, error) {
+	if size == "max" {
+		return -1, nil
+	}
+
+	multiplier := int64(1)
+	numeric := strings.TrimSpace(size)
+	if numeric != "" {
+		switch strings.ToUpper(numeric[len(numeric)-1:]) {
+		case "K":
+			multiplier = 1 << 10
+		case "M":
+			multiplier = 1 << 20
+		case "G":
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			numeric = numeric[:len(numeric)-1]
+		}
+	}
+
+	value, err := strconv.ParseInt(numeric, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a positive number of bytes with optional K, M or G suffix", size)
+	}
+
+	return value * multiplier, nil
+}
+
+func (c *CgroupConfig) validate() error {
+	var memoryMax, memoryHigh int64
+
+	if c.MemoryMax != "" {
+		size, err := ParseByteSize(c.MemoryMax)
+		if err != nil {
+			return fmt.Errorf("cgroup memory-max: %w", err)
+		}
+		memoryMax = size
+	}
+
+	if c.MemoryHigh != "" {
+		size, err := ParseByteSize(c.MemoryHigh)
+		if err != nil {
+			return fmt.Errorf("cgroup memory-high: %w", err)
+		}
+		memoryHigh = size
+	}
+
+	if memoryMax > 0 && memoryHigh > memoryMax {
+		return fmt.Errorf("cgroup memory-high must be <= memory-max")
+	}
+
+	if c.CPUWeight != 0 && (c.CPUWeight < 1 || c.CPUWeight > 10000) {
+		return fmt.Errorf("cgroup cpu-weight must be between 1 and 10000")
+	}
+
+	if c.CPUQuota < 0 {
+		return fmt.Errorf("cgroup cpu-quota must be >= 0")
+	}
+
+	if c.PidsMax < 0 {
+		return fmt.Errorf("cgroup pids-max must be >= 0")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        string
+		expected    int64
+		expectError bool
+	}{
+		{name: "plain bytes", size: "1048576", expected: 1048576},
+		{name: "kibibytes", size: "64K", expected: 64 << 10},
+		{name: "mebibytes", size: "512M", expected: 512 << 20},
+		{name: "gibibytes lowercase", size: "2g", expected: 2 << 30},
+		{name: "no limit", size: "max", expected: -1},
+		{name: "empty", size: "", expectError: true},
+		{name: "zero", size: "0", expectError: true},
+		{name: "negative", size: "-1M", expectError: true},
+		{name: "unknown suffix", size: "1T", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := ParseByteSize(tt.size)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, size)
+			}
+		})
+	}
+}
+
+func TestCgroupConfigValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        CgroupConfig
+		expectedError string
+	}{
+		{
+			name: "valid limits",
+			config: CgroupConfig{
+				MemoryMax:  "512M",
+				MemoryHigh: "384M",
+				CPUWeight:  200,
+				CPUQuota:   0.5,
+				PidsMax:    128,
+			},
+		},
+		{
+			name:          "invalid memory-max",
+			config:        CgroupConfig{MemoryMax: "lots"},
+			expectedError: "cgroup memory-max: invalid size",
+		},
+		{
+			name:          "memory-high above memory-max",
+			config:        CgroupConfig{MemoryMax: "256M", MemoryHigh: "512M"},
+			expectedError: "cgroup memory-high must be <= memory-max",
+		},
+		{
+			name:          "cpu-weight out of range",
+			config:        CgroupConfig{CPUWeight: 20000},
+			expectedError: "cgroup cpu-weight must be between 1 and 10000",
+		},
+		{
+			name:          "negative cpu-quota",
+			config:        CgroupConfig{CPUQuota: -1},
+			expectedError: "cgroup cpu-quota must be >= 0",
+		},
+		{
+			name:          "negative pids-max",
+			config:        CgroupConfig{PidsMax: -1},
+			expectedError: "cgroup pids-max must be >= 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate()
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CheckCommand checks that the file run as `command` exists and is executable,
+// resolving it like VerifyIntegrity. A command given by name in a sandbox is
+// resolved only within the sandbox on launch, so it is not checked.
+func (c *RunnerConfig) CheckCommand() error {
+	if c.Sandbox != nil && !strings.Contains(c.Command, "/") {
+		return nil
+	}
+
+	path, err := c.commandPath()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("command: %w", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("command: %s is a directory", path)
+	}
+
+	if info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("command: %s is not executable", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	runnerPath := writeRunnerFile(t, dir, "runner", "#!/bin/sh\n")
+	dataPath := filepath.Join(dir, "data.txt")
+	require.NoError(t, os.WriteFile(dataPath, nil, 0o600))
+	t.Setenv("PATH", dir)
+
+	tests := []struct {
+		name          string
+		config        RunnerConfig
+		expectedError string
+	}{
+		{
+			name:   "absolute command",
+			config: RunnerConfig{Command: runnerPath},
+		},
+		{
+			name:   "relative command",
+			config: RunnerConfig{WorkDir: dir, Command: "./runner"},
+		},
+		{
+			name:   "command in PATH",
+			config: RunnerConfig{Command: "runner"},
+		},
+		{
+			name:   "sandboxed command by name",
+			config: RunnerConfig{Command: "missing", Sandbox: &SandboxConfig{}},
+		},
+		{
+			name:          "missing command",
+			config:        RunnerConfig{Command: filepath.Join(dir, "missing")},
+			expectedError: "no such file or directory",
+		},
+		{
+			name:          "command not in PATH",
+			config:        RunnerConfig{Command: "missing"},
+			expectedError: "executable file not found",
+		},
+		{
+			name:          "directory",
+			config:        RunnerConfig{Command: dir},
+			expectedError: "is a directory",
+		},
+		{
+			name:          "not executable",
+			config:        RunnerConfig{Command: dataPath},
+			expectedError: "is not executable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.CheckCommand()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
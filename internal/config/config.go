@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
+	"syscall"
 	"task-runner-launcher/internal/errs"
 	"task-runner-launcher/internal/logs"
 
@@ -16,6 +19,22 @@ import (
 const (
 	// EnvVarHealthCheckPort is the env var for the port for the launcher's health check server.
 	EnvVarHealthCheckPort = "N8N_RUNNERS_LAUNCHER_HEALTH_CHECK_PORT"
+
+	// EnvVarGracePeriod is the env var for how long (in seconds) a runner may take
+	// to exit after being asked to terminate.
+	EnvVarGracePeriod = "N8N_RUNNERS_LAUNCHER_GRACE_PERIOD"
+
+	// EnvVarCrashWindow is the env var for how long (in seconds) after launch a
+	// runner's failed exit counts as a crash.
+	EnvVarCrashWindow = "N8N_RUNNERS_LAUNCHER_CRASH_WINDOW"
+
+	// EnvVarCrashThreshold is the env var for the number of consecutive crashes
+	// of a runner type at which the launcher opens its circuit.
+	EnvVarCrashThreshold = "N8N_RUNNERS_LAUNCHER_CRASH_THRESHOLD"
+
+	// EnvVarCrashCooldown is the env var for how long (in seconds) a runner
+	// type's circuit stays open.
+	EnvVarCrashCooldown = "N8N_RUNNERS_LAUNCHER_CRASH_COOLDOWN"
 )
 
 // LauncherConfig holds the full configuration for the launcher.
@@ -41,12 +60,47 @@ type BaseConfig struct {
 	// aborted.
 	TaskTimeout string `env:"N8N_RUNNERS_TASK_TIMEOUT, default=60"`
 
-	// TaskBrokerURI is the URI of the task broker server.
+	// GracePeriod is how long (in seconds) a runner may take to finish its
+	// current task and exit after receiving SIGTERM, before it is killed.
+	GracePeriod int `env:"N8N_RUNNERS_LAUNCHER_GRACE_PERIOD, default=20"`
+
+	// CrashWindow is how long (in seconds) after launch a runner may exit with
+	// an error or on a signal for its launch to count as failed.
+	CrashWindow int `env:"N8N_RUNNERS_LAUNCHER_CRASH_WINDOW, default=10"`
+
+	// CrashThreshold is the number of consecutive failed launches of a runner
+	// type at which the launcher stops offering tasks for the runner type.
+	CrashThreshold int `env:"N8N_RUNNERS_LAUNCHER_CRASH_THRESHOLD, default=5"`
+
+	// CrashCooldown is how long (in seconds) the launcher stops offering tasks
+	// for a runner type after reaching the crash threshold.
+	CrashCooldown int `env:"N8N_RUNNERS_LAUNCHER_CRASH_COOLDOWN, default=60"`
+
+	// TaskBrokerURI is the URI of the task broker server, either `http(s)://`
+	// or `unix:///path/to.sock` for a unix domain socket.
 	TaskBrokerURI string `env:"N8N_RUNNERS_TASK_BROKER_URI, default=http://127.0.0.1:5679"`
 
+	// TaskBrokerURIs is a comma-separated list of task broker URIs to fail over
+	// between, in order of priority or weighted, taking precedence over
+	// TaskBrokerURI if set.
+	TaskBrokerURIs string `env:"N8N_RUNNERS_TASK_BROKER_URIS"`
+
+	// Brokers are the task brokers parsed from TaskBrokerURIs, if set.
+	Brokers []Broker
+
+	// TLS is the config for connections to the task broker over `https`.
+	TLS *TLSConfig
+
+	// Proxy is the config for connections to the task broker via a proxy.
+	Proxy *ProxyConfig
+
 	// HealthCheckServerPort is the port for the launcher's health check server.
 	HealthCheckServerPort string `env:"N8N_RUNNERS_LAUNCHER_HEALTH_CHECK_PORT, default=5680"`
 
+	// AdminToken is the bearer token required by the admin API, served on the
+	// launcher's health check server. The admin API is disabled if unset.
+	AdminToken string `env:"N8N_RUNNERS_LAUNCHER_ADMIN_TOKEN"`
+
 	// RunnerHealthCheckServerHost is the host for all runners' health check servers.
 	RunnerHealthCheckServerHost string `env:"N8N_RUNNERS_HEALTH_CHECK_SERVER_HOST, default=127.0.0.1"`
 
@@ -90,11 +144,162 @@ type RunnerConfig struct {
 
 	// Env vars for the launcher to set directly on the runner.
 	EnvOverrides map[string]string `json:"env-overrides"`
+
+	// Number of runners to keep started and registered with the task broker at
+	// all times, each replaced as soon as it exits. Default: 0, i.e. runners
+	// are launched on demand only.
+	MinIdle int `json:"min-idle,omitempty"`
+
+	// Max number of runners to keep running at the same time. While fewer runners
+	// are running, the launcher keeps a task offer registered with the task broker
+	// and launches an extra runner when the offer is accepted. Default: 1, or
+	// `min-idle` if higher.
+	MaxConcurrency int `json:"max-concurrency,omitempty"`
+
+	// Resource limits to apply to each launch of the runner via a dedicated
+	// cgroup, if cgroup v2 is available to the launcher.
+	Cgroup *CgroupConfig `json:"cgroup,omitempty"`
+
+	// Resource limits to set on each launch of the runner, keyed by rlimit name,
+	// e.g. `RLIMIT_NOFILE`. Inherited by the runner's whole process tree.
+	Rlimits map[string]Rlimit `json:"rlimits,omitempty"`
+
+	// User to run the runner as, by name or UID. Default: the launcher's user.
+	User string `json:"user,omitempty"`
+
+	// Group to run the runner as, by name or GID. Default: the user's primary group.
+	Group string `json:"group,omitempty"`
+
+	// Supplementary groups to run the runner with, by name or GID. Any other
+	// supplementary groups are dropped when `user` is set.
+	SupplementaryGroups []string `json:"supplementary-groups,omitempty"`
+
+	// Namespace sandbox to run each launch of the runner in. Optional.
+	Sandbox *SandboxConfig `json:"sandbox,omitempty"`
+
+	// Seccomp profile to apply to each launch of the runner. Optional.
+	Seccomp *SeccompConfig `json:"seccomp,omitempty"`
+
+	// Max time (in seconds) after launch at which a runner is recycled: the
+	// launcher starts a replacement or re-enters the handshake, and sends the
+	// runner SIGTERM to stop accepting tasks and exit once it has finished its
+	// in-flight tasks. Requires `max-launch-duration`. Default: 0, i.e. runners
+	// are not recycled.
+	MaxLifetime int `json:"max-lifetime,omitempty"`
+
+	// Max time (in seconds) after launch by which a runner must have exited,
+	// before it is terminated regardless of in-flight tasks, or killed if still
+	// draining. Default: 0, i.e. no limit.
+	MaxLaunchDuration int `json:"max-launch-duration,omitempty"`
+
+	// Whether to create an empty scratch dir under `workdir` for each launch of
+	// the runner, passed to the runner via N8N_RUNNERS_SCRATCH_DIR and deleted
+	// once the runner has exited.
+	ScratchDir bool `json:"scratch-dir,omitempty"`
+
+	// Hex-encoded SHA-256 hash that the file run as `command` must match, on
+	// load and before each launch. Optional.
+	CommandSHA256 string `json:"command-sha256,omitempty"`
+
+	// Hex-encoded SHA-256 hashes that the files passed in `args` must match, on
+	// load and before each launch, keyed by arg. Optional.
+	ArgsSHA256 map[string]string `json:"args-sha256,omitempty"`
+
+	// Command to run before each launch of the runner. If the hook fails, the
+	// launch is aborted and counts as failed. Optional.
+	PreLaunch *HookConfig `json:"pre-launch,omitempty"`
+
+	// Command to run after each launch of the runner has exited. Optional.
+	PostExit *HookConfig `json:"post-exit,omitempty"`
+
+	// URI of the task broker for this runner type to connect to, overriding
+	// N8N_RUNNERS_TASK_BROKER_URI and N8N_RUNNERS_TASK_BROKER_URIS. Optional.
+	TaskBrokerURI string `json:"task-broker-uri,omitempty"`
+
+	// Path to a file containing the auth token for this runner type to send to
+	// the task broker, overriding N8N_RUNNERS_AUTH_TOKEN. Optional.
+	AuthTokenFile string `json:"auth-token-file,omitempty"`
+
+	// Credential resolved from `user`, `group` and `supplementary-groups` on load.
+	// Nil if the runner is to run with the launcher's credential.
+	Credential *syscall.Credential `json:"-"`
+
+	// Auth token read from `auth-token-file` on load, if set.
+	AuthToken string `json:"-"`
+}
+
+// HealthCheckServerPorts returns the ports reserved for the health check servers
+// of the runner's instances, i.e. one port per instance in a consecutive range
+// starting at the runner's `health-check-server-port`. If the runner is recycled,
+// a second port per instance follows, for a replacement to use while the
+// recycled runner is draining.
+func (c *RunnerConfig) HealthCheckServerPorts() []string {
+	basePort, err := strconv.Atoi(c.HealthCheckServerPort)
+	if err != nil {
+		return []string{c.HealthCheckServerPort}
+	}
+
+	portsPerInstance := 1
+	if c.MaxLifetime > 0 {
+		portsPerInstance = 2
+	}
+
+	ports := make([]string, c.MaxInstances()*portsPerInstance)
+	for i := range ports {
+		ports[i] = strconv.Itoa(basePort + i)
+	}
+
+	return ports
+}
+
+// InstanceHealthCheckServerPorts returns the health check server ports reserved
+// for the given instance of the runner.
+func (c *RunnerConfig) InstanceHealthCheckServerPorts(instance int) []string {
+	ports := c.HealthCheckServerPorts()
+	if len(ports) == 1 {
+		return ports
+	}
+
+	instancePorts := []string{ports[instance]}
+	if c.MaxLifetime > 0 {
+		instancePorts = append(instancePorts, ports[instance+c.MaxInstances()])
+	}
+
+	return instancePorts
+}
+
+// MaxInstances returns the max number of instances of the runner that may be
+// running at the same time.
+func (c *RunnerConfig) MaxInstances() int {
+	return max(1, c.MinIdle, c.MaxConcurrency)
+}
+
+// HasCgroupLimits returns whether any runner is configured with cgroup limits.
+func (c *LauncherConfig) HasCgroupLimits() bool {
+	for _, runnerConfig := range c.RunnerConfigs {
+		if runnerConfig.Cgroup != nil {
+			return true
+		}
+	}
+
+	return false
 }
 
 // LoadLauncherConfig loads the launcher's base config from the launcher's environment and
-// loads runner configs from the config file specified by N8N_RUNNERS_CONFIG_PATH.
+// loads runner configs from the config file specified by N8N_RUNNERS_CONFIG_PATH,
+// for all runner types in the config file if none are given.
 func LoadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (*LauncherConfig, error) {
+	return loadLauncherConfig(runnerTypes, baseLookuper, false)
+}
+
+// ValidateLauncherConfig loads the launcher's config like LoadLauncherConfig,
+// and additionally checks that each runner's command exists and is executable.
+// Returns all validation errors at once.
+func ValidateLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (*LauncherConfig, error) {
+	return loadLauncherConfig(runnerTypes, baseLookuper, true)
+}
+
+func loadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper, checkCommands bool) (*LauncherConfig, error) {
 	ctx := context.Background()
 
 	var baseConfig BaseConfig
@@ -107,7 +312,19 @@ func LoadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (
 
 	var cfgErrs []error
 
-	if err := validateURL(baseConfig.TaskBrokerURI, "N8N_RUNNERS_TASK_BROKER_URI"); err != nil {
+	if err := validateTaskBrokerURI(baseConfig.TaskBrokerURI, "N8N_RUNNERS_TASK_BROKER_URI"); err != nil {
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	if baseConfig.TaskBrokerURIs != "" {
+		brokers, err := parseBrokers(baseConfig.TaskBrokerURIs, "N8N_RUNNERS_TASK_BROKER_URIS")
+		if err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+		baseConfig.Brokers = brokers
+	}
+
+	if _, err := baseConfig.TLS.ClientConfig(); err != nil {
 		cfgErrs = append(cfgErrs, err)
 	}
 
@@ -118,6 +335,22 @@ func LoadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (
 		cfgErrs = append(cfgErrs, errs.ErrNegativeAutoShutdownTimeout)
 	}
 
+	if baseConfig.GracePeriod < 0 {
+		cfgErrs = append(cfgErrs, fmt.Errorf("%s must be >= 0", EnvVarGracePeriod))
+	}
+
+	if baseConfig.CrashWindow < 0 {
+		cfgErrs = append(cfgErrs, fmt.Errorf("%s must be >= 0", EnvVarCrashWindow))
+	}
+
+	if baseConfig.CrashThreshold <= 0 {
+		cfgErrs = append(cfgErrs, fmt.Errorf("%s must be > 0", EnvVarCrashThreshold))
+	}
+
+	if baseConfig.CrashCooldown < 0 {
+		cfgErrs = append(cfgErrs, fmt.Errorf("%s must be >= 0", EnvVarCrashCooldown))
+	}
+
 	if port, err := strconv.Atoi(baseConfig.HealthCheckServerPort); err != nil || port <= 0 || port >= 65536 {
 		cfgErrs = append(cfgErrs, fmt.Errorf("%s must be a valid port number", EnvVarHealthCheckPort))
 	}
@@ -132,7 +365,7 @@ func LoadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (
 
 	// runners
 
-	runnerConfigs, err := readLauncherConfigFile(baseConfig.ConfigPath, runnerTypes)
+	runnerConfigs, err := readLauncherConfigFile(baseConfig.ConfigPath, runnerTypes, checkCommands)
 	if err != nil {
 		cfgErrs = append(cfgErrs, err)
 	}
@@ -148,8 +381,10 @@ func LoadLauncherConfig(runnerTypes []string, baseLookuper envconfig.Lookuper) (
 }
 
 // readLauncherConfigFile reads the config file at the specified path and
-// returns the runner config(s) for the requested runner type(s).
-func readLauncherConfigFile(configPath string, runnerTypes []string) (map[string]*RunnerConfig, error) {
+// returns the runner config(s) for the requested runner type(s), or for all
+// runner types in the file if none are requested. Returns all validation errors
+// at once.
+func readLauncherConfigFile(configPath string, runnerTypes []string, checkCommands bool) (map[string]*RunnerConfig, error) {
 	// #nosec G304 -- configPath is controlled by system administrator via environment variable
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -169,6 +404,14 @@ func readLauncherConfigFile(configPath string, runnerTypes []string) (map[string
 		return nil, fmt.Errorf("config file at %s contains no task runners", configPath)
 	}
 
+	if len(runnerTypes) == 0 {
+		for _, runnerConfig := range fileConfig.TaskRunners {
+			runnerTypes = append(runnerTypes, runnerConfig.RunnerType)
+		}
+	}
+
+	var cfgErrs []error
+
 	runnerConfigs := make(map[string]*RunnerConfig)
 	for _, runnerType := range runnerTypes {
 		found := false
@@ -180,7 +423,7 @@ func readLauncherConfigFile(configPath string, runnerTypes []string) (map[string
 			}
 		}
 		if !found {
-			return nil, fmt.Errorf("config file at %s does not contain requested runner type: %s", configPath, runnerType)
+			cfgErrs = append(cfgErrs, fmt.Errorf("config file at %s does not contain requested runner type: %s", configPath, runnerType))
 		}
 	}
 
@@ -190,16 +433,26 @@ func readLauncherConfigFile(configPath string, runnerTypes []string) (map[string
 				config.HealthCheckServerPort = "5681"
 			}
 		}
-	} else {
-		for runnerType, config := range runnerConfigs {
-			if config.HealthCheckServerPort == "" {
-				return nil, fmt.Errorf("runner %s: health-check-server-port is required with multiple runners", runnerType)
-			}
+	}
+
+	for _, runnerType := range slices.Sorted(maps.Keys(runnerConfigs)) {
+		config := runnerConfigs[runnerType]
+
+		if len(runnerConfigs) > 1 && config.HealthCheckServerPort == "" {
+			cfgErrs = append(cfgErrs, fmt.Errorf("runner %s: health-check-server-port is required with multiple runners", runnerType))
+		}
+
+		for _, err := range config.validate(runnerType, checkCommands) {
+			cfgErrs = append(cfgErrs, fmt.Errorf("runner %s: %w", runnerType, err))
 		}
 	}
 
 	if err := validateRunnerPorts(runnerConfigs); err != nil {
-		return nil, err
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	if len(cfgErrs) > 0 {
+		return nil, errors.Join(cfgErrs...)
 	}
 
 	if taskRunnersNum == 1 {
@@ -211,6 +464,101 @@ func readLauncherConfigFile(configPath string, runnerTypes []string) (map[string
 	return runnerConfigs, nil
 }
 
+// validate validates the runner config, resolving the runner's user and seccomp
+// profile, and returns every validation error found. Integrity is checked only
+// if all other checks pass, since it depends on the resolved config.
+func (c *RunnerConfig) validate(runnerType string, checkCommand bool) []error {
+	var cfgErrs []error
+
+	if c.MinIdle < 0 {
+		cfgErrs = append(cfgErrs, errors.New("min-idle must be >= 0"))
+	}
+
+	if c.MaxConcurrency < 0 {
+		cfgErrs = append(cfgErrs, errors.New("max-concurrency must be >= 0"))
+	}
+
+	if c.MaxConcurrency > 0 && c.MaxConcurrency < c.MinIdle {
+		cfgErrs = append(cfgErrs, errors.New("max-concurrency must be >= min-idle"))
+	}
+
+	if c.MaxLifetime < 0 {
+		cfgErrs = append(cfgErrs, errors.New("max-lifetime must be >= 0"))
+	}
+
+	if c.MaxLaunchDuration < 0 {
+		cfgErrs = append(cfgErrs, errors.New("max-launch-duration must be >= 0"))
+	}
+
+	if c.MaxLifetime > 0 && c.MaxLaunchDuration == 0 {
+		cfgErrs = append(cfgErrs, errors.New("max-launch-duration is required with max-lifetime, to bound how long a recycled runner may take to drain"))
+	}
+
+	if c.MaxLifetime > 0 && c.MaxLaunchDuration > 0 && c.MaxLaunchDuration <= c.MaxLifetime {
+		cfgErrs = append(cfgErrs, errors.New("max-launch-duration must be > max-lifetime"))
+	}
+
+	if c.PreLaunch != nil {
+		if err := c.PreLaunch.validate("pre-launch"); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if c.PostExit != nil {
+		if err := c.PostExit.validate("post-exit"); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if c.Cgroup != nil {
+		if err := c.Cgroup.validate(); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if err := validateRlimits(c.Rlimits); err != nil {
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	if c.TaskBrokerURI != "" {
+		if err := validateTaskBrokerURI(c.TaskBrokerURI, "task-broker-uri"); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if err := c.resolveAuthToken(); err != nil {
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	if err := c.resolveCredential(); err != nil {
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	if c.Sandbox != nil {
+		if err := c.Sandbox.validate(c); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if c.Seccomp != nil {
+		if err := c.Seccomp.resolve(runnerType); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if checkCommand {
+		if err := c.CheckCommand(); err != nil {
+			cfgErrs = append(cfgErrs, err)
+		}
+	}
+
+	if err := c.validateIntegrity(); err != nil {
+		cfgErrs = append(cfgErrs, err)
+	}
+
+	return cfgErrs
+}
+
 func validateRunnerPorts(runnerConfigs map[string]*RunnerConfig) error {
 	reservedPorts := map[string]string{
 		"5678": "n8n main server",
@@ -218,25 +566,33 @@ func validateRunnerPorts(runnerConfigs map[string]*RunnerConfig) error {
 		"5680": "launcher health check server",
 	}
 
+	var cfgErrs []error
 	usedPorts := make(map[string]string)
 
-	for runnerType, config := range runnerConfigs {
-		port := config.HealthCheckServerPort
-
-		if port, err := strconv.Atoi(port); err != nil || port <= 0 || port >= 65536 {
-			return fmt.Errorf("runner %s: health-check-server-port must be a valid port number", runnerType)
+	for _, runnerType := range slices.Sorted(maps.Keys(runnerConfigs)) {
+		if runnerConfigs[runnerType].HealthCheckServerPort == "" {
+			continue // reported as missing
 		}
 
-		if service, exists := reservedPorts[port]; exists {
-			return fmt.Errorf("runner %s: health-check-server-port %s conflicts with %s", runnerType, port, service)
-		}
+		for _, port := range runnerConfigs[runnerType].HealthCheckServerPorts() {
+			if port, err := strconv.Atoi(port); err != nil || port <= 0 || port >= 65536 {
+				cfgErrs = append(cfgErrs, fmt.Errorf("runner %s: health-check-server-port must be a valid port number", runnerType))
+				break
+			}
 
-		if existingRunner, exists := usedPorts[port]; exists {
-			return fmt.Errorf("runners %s and %s cannot use the same health-check-server-port %s", existingRunner, runnerType, port)
-		}
+			if service, exists := reservedPorts[port]; exists {
+				cfgErrs = append(cfgErrs, fmt.Errorf("runner %s: health-check-server-port %s conflicts with %s", runnerType, port, service))
+				continue
+			}
 
-		usedPorts[port] = runnerType
+			if existingRunner, exists := usedPorts[port]; exists {
+				cfgErrs = append(cfgErrs, fmt.Errorf("runners %s and %s cannot use the same health-check-server-port %s", existingRunner, runnerType, port))
+				continue
+			}
+
+			usedPorts[port] = runnerType
+		}
 	}
 
-	return nil
+	return errors.Join(cfgErrs...)
 }
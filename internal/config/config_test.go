@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sethvargo/go-envconfig"
 	"github.com/stretchr/testify/assert"
@@ -55,6 +56,31 @@ func TestLoadConfig(t *testing.T) {
 			runnerType:    "javascript",
 			expectedError: false,
 		},
+		{
+			name:          "valid TLS configuration",
+			configContent: validConfigContent,
+			envVars: map[string]string{
+				"N8N_RUNNERS_AUTH_TOKEN":              "test-token",
+				"N8N_RUNNERS_TASK_BROKER_URI":         "https://broker.internal:5679",
+				"N8N_RUNNERS_TASK_BROKER_SERVER_NAME": "broker.internal",
+				"N8N_RUNNERS_CONFIG_PATH":             testConfigPath,
+			},
+			runnerType:    "javascript",
+			expectedError: false,
+		},
+		{
+			name:          "invalid TLS configuration",
+			configContent: validConfigContent,
+			envVars: map[string]string{
+				"N8N_RUNNERS_AUTH_TOKEN":          "test-token",
+				"N8N_RUNNERS_TASK_BROKER_URI":     "https://broker.internal:5679",
+				"N8N_RUNNERS_TASK_BROKER_CA_CERT": "not a cert",
+				"N8N_RUNNERS_CONFIG_PATH":         testConfigPath,
+			},
+			runnerType:    "javascript",
+			expectedError: true,
+			errorMsg:      "N8N_RUNNERS_TASK_BROKER_CA_CERT must contain at least one PEM-encoded cert",
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,6 +200,29 @@ func TestValidateRunnerPorts(t *testing.T) {
 			},
 			expectedError: "conflicts with n8n broker server",
 		},
+		{
+			name: "valid non-overlapping port ranges",
+			runnerConfigs: map[string]*RunnerConfig{
+				"javascript": {HealthCheckServerPort: "5681", MinIdle: 2},
+				"python":     {HealthCheckServerPort: "5683", MinIdle: 2},
+			},
+			expectedError: "",
+		},
+		{
+			name: "overlapping port ranges",
+			runnerConfigs: map[string]*RunnerConfig{
+				"javascript": {HealthCheckServerPort: "5681", MinIdle: 3},
+				"python":     {HealthCheckServerPort: "5683"},
+			},
+			expectedError: "cannot use the same health-check-server-port 5683",
+		},
+		{
+			name: "port range reaching reserved port",
+			runnerConfigs: map[string]*RunnerConfig{
+				"javascript": {HealthCheckServerPort: "5676", MinIdle: 3},
+			},
+			expectedError: "conflicts with n8n main server",
+		},
 		{
 			name: "invalid port number",
 			runnerConfigs: map[string]*RunnerConfig{
@@ -255,7 +304,7 @@ func TestBackwardsCompatibilityPortDefaults(t *testing.T) {
 			err := os.WriteFile(testConfigPath, []byte(tt.configContent), 0600)
 			require.NoError(t, err)
 
-			configs, err := readLauncherConfigFile(testConfigPath, tt.runnerTypes)
+			configs, err := readLauncherConfigFile(testConfigPath, tt.runnerTypes, false)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -268,3 +317,218 @@ func TestBackwardsCompatibilityPortDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthCheckServerPorts(t *testing.T) {
+	tests := []struct {
+		name          string
+		runnerConfig  RunnerConfig
+		expectedPorts []string
+	}{
+		{
+			name:          "single port for on-demand runner",
+			runnerConfig:  RunnerConfig{HealthCheckServerPort: "5681"},
+			expectedPorts: []string{"5681"},
+		},
+		{
+			name:          "one port per warm runner",
+			runnerConfig:  RunnerConfig{HealthCheckServerPort: "5681", MinIdle: 3},
+			expectedPorts: []string{"5681", "5682", "5683"},
+		},
+		{
+			name:          "spare port per recycled runner",
+			runnerConfig:  RunnerConfig{HealthCheckServerPort: "5681", MinIdle: 2, MaxLifetime: 3600},
+			expectedPorts: []string{"5681", "5682", "5683", "5684"},
+		},
+		{
+			name:          "invalid port left for validation",
+			runnerConfig:  RunnerConfig{HealthCheckServerPort: "not-a-port", MinIdle: 3},
+			expectedPorts: []string{"not-a-port"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedPorts, tt.runnerConfig.HealthCheckServerPorts())
+		})
+	}
+}
+
+func TestInstanceHealthCheckServerPorts(t *testing.T) {
+	runnerConfig := RunnerConfig{HealthCheckServerPort: "5681", MinIdle: 2}
+	assert.Equal(t, []string{"5682"}, runnerConfig.InstanceHealthCheckServerPorts(1))
+
+	runnerConfig.MaxLifetime = 3600
+	assert.Equal(t, []string{"5682", "5684"}, runnerConfig.InstanceHealthCheckServerPorts(1))
+}
+
+func TestNegativeMinIdle(t *testing.T) {
+	testConfigPath := filepath.Join(t.TempDir(), "test-config.json")
+	configContent := `{
+		"task-runners": [{
+			"runner-type": "javascript",
+			"workdir": "/test",
+			"command": "node",
+			"args": ["test.js"],
+			"min-idle": -1
+		}]
+	}`
+	require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0600))
+
+	_, err := readLauncherConfigFile(testConfigPath, []string{"javascript"}, false)
+
+	assert.ErrorContains(t, err, "runner javascript: min-idle must be >= 0")
+}
+
+func TestRecyclingLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		limits        string
+		expectedError string
+	}{
+		{
+			name:          "max-lifetime only",
+			limits:        `"max-lifetime": 3600`,
+			expectedError: "runner javascript: max-launch-duration is required with max-lifetime",
+		},
+		{
+			name:   "max-launch-duration after max-lifetime",
+			limits: `"max-lifetime": 3600, "max-launch-duration": 3900`,
+		},
+		{
+			name:          "negative max-lifetime",
+			limits:        `"max-lifetime": -1`,
+			expectedError: "runner javascript: max-lifetime must be >= 0",
+		},
+		{
+			name:          "negative max-launch-duration",
+			limits:        `"max-launch-duration": -1`,
+			expectedError: "runner javascript: max-launch-duration must be >= 0",
+		},
+		{
+			name:          "max-launch-duration before max-lifetime",
+			limits:        `"max-lifetime": 3600, "max-launch-duration": 3600`,
+			expectedError: "runner javascript: max-launch-duration must be > max-lifetime",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfigPath := filepath.Join(t.TempDir(), "test-config.json")
+			configContent := `{
+				"task-runners": [{
+					"runner-type": "javascript",
+					"workdir": "/test",
+					"command": "node",
+					"args": ["test.js"],
+					` + tt.limits + `
+				}]
+			}`
+			require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0600))
+
+			_, err := readLauncherConfigFile(testConfigPath, []string{"javascript"}, false)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		name          string
+		hooks         string
+		expectedError string
+	}{
+		{
+			name:  "valid hooks",
+			hooks: `"pre-launch": {"command": "/usr/local/bin/refresh-creds", "timeout": 10}, "post-exit": {"command": "upload-dump", "args": ["--all"]}`,
+		},
+		{
+			name:          "missing command",
+			hooks:         `"pre-launch": {"args": ["--all"]}`,
+			expectedError: "runner javascript: pre-launch hook: command is required",
+		},
+		{
+			name:          "negative timeout",
+			hooks:         `"post-exit": {"command": "upload-dump", "timeout": -1}`,
+			expectedError: "runner javascript: post-exit hook: timeout must be >= 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfigPath := filepath.Join(t.TempDir(), "test-config.json")
+			configContent := `{
+				"task-runners": [{
+					"runner-type": "javascript",
+					"workdir": "/test",
+					"command": "node",
+					"args": ["test.js"],
+					` + tt.hooks + `
+				}]
+			}`
+			require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0600))
+
+			configs, err := readLauncherConfigFile(testConfigPath, []string{"javascript"}, false)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 10*time.Second, configs["javascript"].PreLaunch.TimeoutDuration())
+			assert.Equal(t, DefaultHookTimeout, configs["javascript"].PostExit.TimeoutDuration())
+		})
+	}
+}
+
+func TestValidateConfigReportsAllErrors(t *testing.T) {
+	dir := t.TempDir()
+	testConfigPath := filepath.Join(dir, "testconfig.json")
+	configContent := `{
+		"task-runners": [
+			{
+				"runner-type": "javascript",
+				"workdir": "` + dir + `",
+				"command": "` + filepath.Join(dir, "missing") + `",
+				"min-idle": -1,
+				"health-check-server-port": "5681"
+			},
+			{
+				"runner-type": "python",
+				"workdir": "` + dir + `",
+				"command": "` + filepath.Join(dir, "runner") + `",
+				"max-lifetime": -1
+			}
+		]
+	}`
+	require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "runner"), []byte("#!/bin/sh\n"), 0o600))
+
+	lookuper := envconfig.MapLookuper(map[string]string{
+		"N8N_RUNNERS_AUTH_TOKEN":      "test-token",
+		"N8N_RUNNERS_TASK_BROKER_URI": "not-a-url",
+		"N8N_RUNNERS_CONFIG_PATH":     testConfigPath,
+	})
+
+	_, err := ValidateLauncherConfig(nil, lookuper)
+	require.Error(t, err)
+
+	for _, expected := range []string{
+		"N8N_RUNNERS_TASK_BROKER_URI",
+		"runner javascript: min-idle must be >= 0",
+		"runner javascript: command: stat " + filepath.Join(dir, "missing"),
+		"runner python: health-check-server-port is required with multiple runners",
+		"runner python: max-lifetime must be >= 0",
+		"runner python: command: " + filepath.Join(dir, "runner") + " is not executable",
+	} {
+		assert.ErrorContains(t, err, expected)
+	}
+
+	_, err = LoadLauncherConfig([]string{"javascript"}, lookuper)
+	assert.NotContains(t, err.Error(), "command:", "loading should not check commands")
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// resolveCredential resolves the runner's `user`, `group` and
+// `supplementary-groups` into the credential to start the runner with, leaving
+// the credential unset if none of them are set. A runner given a user but no
+// group runs with the user's primary group. Supplementary groups not listed are
+// dropped.
+func (c *RunnerConfig) resolveCredential() error {
+	if c.User == "" && c.Group == "" && len(c.SupplementaryGroups) == 0 {
+		return nil
+	}
+
+	if c.User == "" {
+		return fmt.Errorf("user is required when setting group or supplementary-groups")
+	}
+
+	uid, primaryGid, err := lookupUser(c.User)
+	if err != nil {
+		return err
+	}
+
+	gid := primaryGid
+	if c.Group != "" {
+		if gid, err = lookupGroup(c.Group); err != nil {
+			return err
+		}
+	}
+	if gid == nil {
+		return fmt.Errorf("group is required for user %s, which has no entry in the user database", c.User)
+	}
+
+	groups := make([]uint32, 0, len(c.SupplementaryGroups))
+	for _, name := range c.SupplementaryGroups {
+		id, err := lookupGroup(name)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, *id)
+	}
+
+	c.Credential = &syscall.Credential{Uid: uid, Gid: *gid, Groups: groups}
+
+	return nil
+}
+
+// lookupUser returns the UID and primary GID of a user given by name or UID.
+// A numeric UID without an entry in the user database has no primary GID.
+func lookupUser(nameOrID string) (uint32, *uint32, error) {
+	u, err := user.Lookup(nameOrID)
+	if err != nil {
+		u, err = user.LookupId(nameOrID)
+	}
+	if err != nil {
+		if id, parseErr := parseID(nameOrID); parseErr == nil {
+			return id, nil, nil
+		}
+		return 0, nil, fmt.Errorf("failed to resolve user %s: %w", nameOrID, err)
+	}
+
+	uid, err := parseID(u.Uid)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to resolve user %s: %w", nameOrID, err)
+	}
+
+	gid, err := parseID(u.Gid)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to resolve primary group of user %s: %w", nameOrID, err)
+	}
+
+	return uid, &gid, nil
+}
+
+// lookupGroup returns the GID of a group given by name or GID.
+func lookupGroup(nameOrID string) (*uint32, error) {
+	g, err := user.LookupGroup(nameOrID)
+	if err != nil {
+		g, err = user.LookupGroupId(nameOrID)
+	}
+	if err != nil {
+		if id, parseErr := parseID(nameOrID); parseErr == nil {
+			return &id, nil
+		}
+		return nil, fmt.Errorf("failed to resolve group %s: %w", nameOrID, err)
+	}
+
+	gid, err := parseID(g.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve group %s: %w", nameOrID, err)
+	}
+
+	return &gid, nil
+}
+
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	return uint32(n), err
+}
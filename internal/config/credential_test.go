@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os/user"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCredential(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+	uid, err := strconv.ParseUint(current.Uid, 10, 32)
+	require.NoError(t, err)
+	gid, err := strconv.ParseUint(current.Gid, 10, 32)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		config             RunnerConfig
+		expectedCredential *syscall.Credential
+		expectedError      string
+	}{
+		{
+			name:               "no user or groups",
+			config:             RunnerConfig{},
+			expectedCredential: nil,
+		},
+		{
+			name:               "user by name with primary group",
+			config:             RunnerConfig{User: current.Username},
+			expectedCredential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}},
+		},
+		{
+			name:               "user by UID with primary group",
+			config:             RunnerConfig{User: current.Uid},
+			expectedCredential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}},
+		},
+		{
+			name: "unknown numeric IDs",
+			config: RunnerConfig{
+				User:                "54321",
+				Group:               "54322",
+				SupplementaryGroups: []string{"54323", current.Gid},
+			},
+			expectedCredential: &syscall.Credential{Uid: 54321, Gid: 54322, Groups: []uint32{54323, uint32(gid)}},
+		},
+		{
+			name:          "unknown numeric UID without group",
+			config:        RunnerConfig{User: "54321"},
+			expectedError: "group is required for user 54321",
+		},
+		{
+			name:          "unknown user",
+			config:        RunnerConfig{User: "no-such-user-for-runner"},
+			expectedError: "failed to resolve user no-such-user-for-runner",
+		},
+		{
+			name:          "unknown group",
+			config:        RunnerConfig{User: current.Username, Group: "no-such-group-for-runner"},
+			expectedError: "failed to resolve group no-such-group-for-runner",
+		},
+		{
+			name:          "group without user",
+			config:        RunnerConfig{Group: current.Gid},
+			expectedError: "user is required when setting group or supplementary-groups",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.resolveCredential()
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedCredential, tt.config.Credential)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultHookTimeout is how long a hook may run for when no timeout is configured.
+const DefaultHookTimeout = 30 * time.Second
+
+// HookConfig holds a command that the launcher runs around each launch of a
+// runner, e.g. to refresh a credentials file or to upload a crash dump.
+type HookConfig struct {
+	// Command to run, resolved via the launcher's PATH if not a path.
+	Command string `json:"command"`
+
+	// Arguments for command.
+	Args []string `json:"args,omitempty"`
+
+	// Max time (in seconds) the hook may run for before it is terminated.
+	// Default: 30.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// TimeoutDuration returns how long the hook may run for.
+func (c *HookConfig) TimeoutDuration() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultHookTimeout
+	}
+
+	return time.Duration(c.Timeout) * time.Second
+}
+
+func (c *HookConfig) validate(name string) error {
+	if c.Command == "" {
+		return fmt.Errorf("%s hook: command is required", name)
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("%s hook: timeout must be >= 0", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// IntegrityError is returned when a runner's file does not match its pinned
+// SHA-256 hash.
+type IntegrityError struct {
+	// Path to the file outside of any sandbox.
+	Path string
+
+	// Hex-encoded SHA-256 hashes pinned in the config and found on disk.
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("sha256 of %s is %s, expected %s", e.Path, e.Actual, e.Expected)
+}
+
+// VerifyIntegrity checks the file run as `command` against `command-sha256`
+// and the files passed in `args` against `args-sha256`, if set. Returns an
+// *IntegrityError on a mismatch, or another error if a file cannot be read.
+func (c *RunnerConfig) VerifyIntegrity() error {
+	if c.CommandSHA256 != "" {
+		path, err := c.commandPath()
+		if err != nil {
+			return err
+		}
+
+		if err := verifySHA256(path, c.CommandSHA256); err != nil {
+			return fmt.Errorf("command: %w", err)
+		}
+	}
+
+	for _, arg := range c.Args {
+		expected, ok := c.ArgsSHA256[arg]
+		if !ok {
+			continue
+		}
+
+		path, err := c.hostFilePath(arg)
+		if err != nil {
+			return fmt.Errorf("arg %s: %w", arg, err)
+		}
+
+		if err := verifySHA256(path, expected); err != nil {
+			return fmt.Errorf("arg %s: %w", arg, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *RunnerConfig) validateIntegrity() error {
+	if c.CommandSHA256 != "" && !isSHA256(c.CommandSHA256) {
+		return fmt.Errorf("command-sha256 must be a hex-encoded SHA-256 hash")
+	}
+
+	for arg, hash := range c.ArgsSHA256 {
+		if !slices.Contains(c.Args, arg) {
+			return fmt.Errorf("args-sha256: %s is not in args", arg)
+		}
+
+		if !isSHA256(hash) {
+			return fmt.Errorf("args-sha256: hash of %s must be a hex-encoded SHA-256 hash", arg)
+		}
+	}
+
+	return c.VerifyIntegrity()
+}
+
+// commandPath returns the path outside of any sandbox of the file run as
+// `command`, resolved via the launcher's PATH if `command` is not a path.
+func (c *RunnerConfig) commandPath() (string, error) {
+	if strings.Contains(c.Command, "/") {
+		path, err := c.hostFilePath(c.Command)
+		if err != nil {
+			return "", fmt.Errorf("command: %w", err)
+		}
+		return path, nil
+	}
+
+	if c.Sandbox != nil {
+		return "", fmt.Errorf("command must be a path to use command-sha256 with a sandbox")
+	}
+
+	path, err := exec.LookPath(c.Command)
+	if err != nil {
+		return "", fmt.Errorf("command: %w", err)
+	}
+
+	return path, nil
+}
+
+// hostFilePath returns the path outside of any sandbox of the given path as
+// seen by the runner, resolving a relative path against `workdir`.
+func (c *RunnerConfig) hostFilePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.WorkDir, path)
+	}
+
+	if c.Sandbox == nil {
+		return path, nil
+	}
+
+	hostPath, _, ok := c.Sandbox.HostPath(path)
+	if !ok {
+		return "", fmt.Errorf("%s is not within any sandbox mount", path)
+	}
+
+	return hostPath, nil
+}
+
+func verifySHA256(path, expected string) error {
+	// #nosec G304 -- path is controlled by system administrator via config file
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for integrity check: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read file for integrity check: %w", err)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &IntegrityError{Path: path, Expected: strings.ToLower(expected), Actual: actual}
+	}
+
+	return nil
+}
+
+func isSHA256(hash string) bool {
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == sha256.Size
+}
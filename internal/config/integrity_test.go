@@ -0,0 +1,210 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRunnerFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o700))
+
+	return path
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestValidateIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	runnerPath := writeRunnerFile(t, dir, "runner", "#!/bin/sh\n")
+	writeRunnerFile(t, dir, "start.js", "console.log('hi')")
+	t.Setenv("PATH", dir)
+
+	runnerHash := sha256Hex("#!/bin/sh\n")
+	startHash := sha256Hex("console.log('hi')")
+	otherHash := sha256Hex("other")
+
+	tests := []struct {
+		name             string
+		config           RunnerConfig
+		expectedError    string
+		expectedMismatch bool
+	}{
+		{
+			name:   "no hashes",
+			config: RunnerConfig{Command: "missing"},
+		},
+		{
+			name:   "absolute command",
+			config: RunnerConfig{Command: runnerPath, CommandSHA256: runnerHash},
+		},
+		{
+			name:   "uppercase hash",
+			config: RunnerConfig{Command: runnerPath, CommandSHA256: strings.ToUpper(runnerHash)},
+		},
+		{
+			name:   "relative command",
+			config: RunnerConfig{WorkDir: dir, Command: "./runner", CommandSHA256: runnerHash},
+		},
+		{
+			name:   "command in PATH",
+			config: RunnerConfig{Command: "runner", CommandSHA256: runnerHash},
+		},
+		{
+			name: "sandboxed command",
+			config: RunnerConfig{
+				Command:       "/app/runner",
+				CommandSHA256: runnerHash,
+				Sandbox:       &SandboxConfig{ReadOnlyMounts: []string{dir + ":/app"}},
+			},
+		},
+		{
+			name: "args",
+			config: RunnerConfig{
+				WorkDir:    dir,
+				Command:    runnerPath,
+				Args:       []string{"--flag", "start.js"},
+				ArgsSHA256: map[string]string{"start.js": startHash},
+			},
+		},
+		{
+			name:             "command mismatch",
+			config:           RunnerConfig{Command: runnerPath, CommandSHA256: otherHash},
+			expectedError:    "command: sha256 of " + runnerPath + " is " + runnerHash + ", expected " + otherHash,
+			expectedMismatch: true,
+		},
+		{
+			name: "arg mismatch",
+			config: RunnerConfig{
+				WorkDir:    dir,
+				Command:    runnerPath,
+				Args:       []string{"start.js"},
+				ArgsSHA256: map[string]string{"start.js": otherHash},
+			},
+			expectedError:    "arg start.js: sha256 of " + filepath.Join(dir, "start.js"),
+			expectedMismatch: true,
+		},
+		{
+			name:          "missing command",
+			config:        RunnerConfig{Command: filepath.Join(dir, "missing"), CommandSHA256: runnerHash},
+			expectedError: "failed to open file for integrity check",
+		},
+		{
+			name:          "invalid command hash",
+			config:        RunnerConfig{Command: runnerPath, CommandSHA256: "abc"},
+			expectedError: "command-sha256 must be a hex-encoded SHA-256 hash",
+		},
+		{
+			name: "invalid arg hash",
+			config: RunnerConfig{
+				Command:    runnerPath,
+				Args:       []string{"start.js"},
+				ArgsSHA256: map[string]string{"start.js": "xyz"},
+			},
+			expectedError: "args-sha256: hash of start.js must be a hex-encoded SHA-256 hash",
+		},
+		{
+			name: "hash for unknown arg",
+			config: RunnerConfig{
+				Command:    runnerPath,
+				Args:       []string{"start.js"},
+				ArgsSHA256: map[string]string{"other.js": startHash},
+			},
+			expectedError: "args-sha256: other.js is not in args",
+		},
+		{
+			name: "sandboxed command by name",
+			config: RunnerConfig{
+				Command:       "runner",
+				CommandSHA256: runnerHash,
+				Sandbox:       &SandboxConfig{ReadOnlyMounts: []string{dir + ":/app"}},
+			},
+			expectedError: "command must be a path to use command-sha256 with a sandbox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validateIntegrity()
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.expectedError)
+			var integrityErr *IntegrityError
+			assert.Equal(t, tt.expectedMismatch, errors.As(err, &integrityErr))
+		})
+	}
+}
+
+func TestVerifyIntegrityDetectsReplacedFile(t *testing.T) {
+	dir := t.TempDir()
+	runnerPath := writeRunnerFile(t, dir, "runner", "approved")
+	config := RunnerConfig{Command: runnerPath, CommandSHA256: sha256Hex("approved")}
+	require.NoError(t, config.VerifyIntegrity())
+
+	writeRunnerFile(t, dir, "runner", "tampered")
+
+	var integrityErr *IntegrityError
+	require.ErrorAs(t, config.VerifyIntegrity(), &integrityErr)
+	assert.Equal(t, sha256Hex("tampered"), integrityErr.Actual)
+}
+
+func TestReadConfigFileVerifiesIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	runnerPath := writeRunnerFile(t, dir, "runner", "tampered")
+	testConfigPath := filepath.Join(dir, "test-config.json")
+	configContent := `{
+		"task-runners": [{
+			"runner-type": "javascript",
+			"workdir": "` + dir + `",
+			"command": "` + runnerPath + `",
+			"command-sha256": "` + sha256Hex("approved") + `"
+		}]
+	}`
+	require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0o600))
+
+	_, err := readLauncherConfigFile(testConfigPath, []string{"javascript"}, false)
+
+	var integrityErr *IntegrityError
+	assert.ErrorAs(t, err, &integrityErr)
+	assert.ErrorContains(t, err, "runner javascript: command: sha256 of "+runnerPath)
+}
+
+func TestReadConfigFileReportsIntegrityWithOtherErrors(t *testing.T) {
+	dir := t.TempDir()
+	runnerPath := writeRunnerFile(t, dir, "runner", "tampered")
+	testConfigPath := filepath.Join(dir, "test-config.json")
+	configContent := `{
+		"task-runners": [{
+			"runner-type": "javascript",
+			"workdir": "` + dir + `",
+			"command": "` + runnerPath + `",
+			"command-sha256": "` + sha256Hex("approved") + `",
+			"min-idle": -1
+		}]
+	}`
+	require.NoError(t, os.WriteFile(testConfigPath, []byte(configContent), 0o600))
+
+	_, err := readLauncherConfigFile(testConfigPath, []string{"javascript"}, false)
+
+	var integrityErr *IntegrityError
+	assert.ErrorAs(t, err, &integrityErr, "Expected integrity to be checked alongside other checks")
+	assert.ErrorContains(t, err, "runner javascript: command: sha256 of "+runnerPath)
+	assert.ErrorContains(t, err, "min-idle must be >= 0")
+}
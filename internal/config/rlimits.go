@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// RlimitInfinity is the value of an rlimit without a limit.
+const RlimitInfinity = math.MaxUint64
+
+// SupportedRlimits are the names of the rlimits that may be set on a runner.
+var SupportedRlimits = []string{
+	"RLIMIT_AS",
+	"RLIMIT_CORE",
+	"RLIMIT_CPU",
+	"RLIMIT_DATA",
+	"RLIMIT_FSIZE",
+	"RLIMIT_MEMLOCK",
+	"RLIMIT_NOFILE",
+	"RLIMIT_NPROC",
+	"RLIMIT_STACK",
+}
+
+// Rlimit holds the soft and hard values of a resource limit. In the config
+// file, an rlimit is either a number or `unlimited`, setting both values, or an
+// object with `soft` and `hard` values.
+type Rlimit struct {
+	Soft uint64
+	Hard uint64
+}
+
+func (r *Rlimit) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var values struct {
+			Soft *rlimitValue `json:"soft"`
+			Hard *rlimitValue `json:"hard"`
+		}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		if values.Soft == nil || values.Hard == nil {
+			return fmt.Errorf("rlimit object requires both `soft` and `hard`")
+		}
+		r.Soft, r.Hard = uint64(*values.Soft), uint64(*values.Hard)
+		return nil
+	}
+
+	var value rlimitValue
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	r.Soft, r.Hard = uint64(value), uint64(value)
+
+	return nil
+}
+
+// rlimitValue is a single rlimit value, either a non-negative number or
+// `unlimited`.
+type rlimitValue uint64
+
+func (v *rlimitValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "unlimited" {
+			return fmt.Errorf("invalid rlimit value %q, expected a non-negative number or `unlimited`", s)
+		}
+		*v = RlimitInfinity
+		return nil
+	}
+
+	var n uint64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid rlimit value %s, expected a non-negative number or `unlimited`", data)
+	}
+	*v = rlimitValue(n)
+
+	return nil
+}
+
+func validateRlimits(rlimits map[string]Rlimit) error {
+	for _, name := range slices.Sorted(maps.Keys(rlimits)) {
+		if !slices.Contains(SupportedRlimits, name) {
+			return fmt.Errorf("unknown rlimit %s, expected one of: %s", name, strings.Join(SupportedRlimits, ", "))
+		}
+
+		if rlimit := rlimits[name]; rlimit.Soft > rlimit.Hard {
+			return fmt.Errorf("rlimit %s: soft value must be <= hard value", name)
+		}
+	}
+
+	return nil
+}
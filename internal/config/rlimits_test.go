@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRlimitUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		expected    Rlimit
+		expectError bool
+	}{
+		{name: "number", json: `1024`, expected: Rlimit{Soft: 1024, Hard: 1024}},
+		{name: "unlimited", json: `"unlimited"`, expected: Rlimit{Soft: RlimitInfinity, Hard: RlimitInfinity}},
+		{name: "soft and hard", json: `{"soft": 10, "hard": "unlimited"}`, expected: Rlimit{Soft: 10, Hard: RlimitInfinity}},
+		{name: "missing hard", json: `{"soft": 10}`, expectError: true},
+		{name: "negative", json: `-1`, expectError: true},
+		{name: "invalid string", json: `"lots"`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rlimit Rlimit
+			err := json.Unmarshal([]byte(tt.json), &rlimit)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, rlimit)
+			}
+		})
+	}
+}
+
+func TestRlimitsValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		rlimits       string
+		expectedError string
+	}{
+		{
+			name:    "valid rlimits",
+			rlimits: `{"RLIMIT_NOFILE": 1024, "RLIMIT_CPU": {"soft": 60, "hard": 70}, "RLIMIT_CORE": 0}`,
+		},
+		{
+			name:          "unknown rlimit",
+			rlimits:       `{"RLIMIT_FOO": 1}`,
+			expectedError: "runner javascript: unknown rlimit RLIMIT_FOO",
+		},
+		{
+			name:          "soft above hard",
+			rlimits:       `{"RLIMIT_NOFILE": {"soft": 2048, "hard": 1024}}`,
+			expectedError: "runner javascript: rlimit RLIMIT_NOFILE: soft value must be <= hard value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			configJSON := `{"task-runners": [{"runner-type": "javascript", "workdir": "/usr/local/bin", ` +
+				`"command": "/usr/local/bin/node", "rlimits": ` + tt.rlimits + `}]}`
+			require.NoError(t, os.WriteFile(configPath, []byte(configJSON), 0600))
+
+			runnerConfigs, err := readLauncherConfigFile(configPath, []string{"javascript"}, false)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, runnerConfigs["javascript"].Rlimits, 3)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// defaultReadOnlyMounts are mounted read-only into a runner's sandbox when no
+// `read-only-mounts` are configured, skipping any that do not exist.
+var defaultReadOnlyMounts = []string{"/bin", "/etc", "/lib", "/lib32", "/lib64", "/opt", "/sbin", "/usr"}
+
+// reservedSandboxPaths are set up by the launcher in every sandbox and may not
+// be used as mount targets.
+var reservedSandboxPaths = []string{"/", "/dev", "/proc"}
+
+// SandboxConfig holds the settings for running a runner in new user, PID,
+// mount, IPC and UTS namespaces, with a root filesystem made up only of the
+// configured mounts. The runner shares the launcher's network namespace, so it
+// can reach the task broker and expose its health check server.
+type SandboxConfig struct {
+	// Paths to mount read-only into the sandbox, each either `path` or
+	// `source:target`. Default: system dirs, e.g. `/usr` and `/etc`.
+	ReadOnlyMounts []string `json:"read-only-mounts,omitempty"`
+
+	// Paths to mount read-write into the sandbox, each either `path` or
+	// `source:target`.
+	ReadWriteMounts []string `json:"read-write-mounts,omitempty"`
+
+	// Whether to give the runner an empty, private `/tmp`.
+	PrivateTmp bool `json:"private-tmp,omitempty"`
+
+	// Hostname inside the sandbox. Default: the launcher's hostname.
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// Mount is a bind mount into a runner's sandbox.
+type Mount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read-only,omitempty"`
+
+	// Whether to skip the mount if the source does not exist.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Mounts returns the bind mounts to set up in the sandbox, in order.
+func (c *SandboxConfig) Mounts() []Mount {
+	var mounts []Mount
+
+	if len(c.ReadOnlyMounts) == 0 {
+		for _, path := range defaultReadOnlyMounts {
+			mounts = append(mounts, Mount{Source: path, Target: path, ReadOnly: true, Optional: true})
+		}
+	}
+
+	for _, spec := range c.ReadOnlyMounts {
+		source, target := parseMount(spec)
+		mounts = append(mounts, Mount{Source: source, Target: target, ReadOnly: true})
+	}
+
+	for _, spec := range c.ReadWriteMounts {
+		source, target := parseMount(spec)
+		mounts = append(mounts, Mount{Source: source, Target: target})
+	}
+
+	return mounts
+}
+
+// Contains returns whether the given path is within any mount target.
+func (c *SandboxConfig) Contains(path string) bool {
+	for _, mount := range c.Mounts() {
+		if isWithin(path, mount.Target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HostPath returns the path outside the sandbox of the given path within the
+// sandbox, via the innermost mount containing it, and whether that mount is
+// read-write. Returns false if the path is not within any mount.
+func (c *SandboxConfig) HostPath(path string) (string, bool, bool) {
+	var innermost *Mount
+	for _, mount := range c.Mounts() {
+		if isWithin(path, mount.Target) && (innermost == nil || len(mount.Target) >= len(innermost.Target)) {
+			innermost = &mount
+		}
+	}
+
+	if innermost == nil {
+		return "", false, false
+	}
+
+	rel, _ := filepath.Rel(innermost.Target, path)
+
+	return filepath.Join(innermost.Source, rel), !innermost.ReadOnly, true
+}
+
+func parseMount(spec string) (string, string) {
+	source, target, found := strings.Cut(spec, ":")
+	if !found {
+		target = source
+	}
+
+	return filepath.Clean(source), filepath.Clean(target)
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func (c *SandboxConfig) validate(runnerConfig *RunnerConfig) error {
+	for _, spec := range append(c.ReadOnlyMounts, c.ReadWriteMounts...) {
+		source, target, found := strings.Cut(spec, ":")
+		if !found {
+			target = source
+		}
+
+		if !filepath.IsAbs(source) || !filepath.IsAbs(target) {
+			return fmt.Errorf("sandbox mount %s: paths must be absolute", spec)
+		}
+
+		for _, reserved := range reservedSandboxPaths {
+			if filepath.Clean(target) == reserved {
+				return fmt.Errorf("sandbox mount %s: cannot mount over %s", spec, reserved)
+			}
+		}
+	}
+
+	if runnerConfig.WorkDir != "" && !c.Contains(runnerConfig.WorkDir) {
+		return fmt.Errorf("sandbox: workdir %s is not within any sandbox mount", runnerConfig.WorkDir)
+	}
+
+	if runnerConfig.ScratchDir {
+		if _, isWritable, _ := c.HostPath(runnerConfig.WorkDir); !isWritable {
+			return fmt.Errorf("sandbox: workdir %s must be within a read-write mount to use scratch-dir", runnerConfig.WorkDir)
+		}
+	}
+
+	if filepath.IsAbs(runnerConfig.Command) && !c.Contains(runnerConfig.Command) {
+		return fmt.Errorf("sandbox: command %s is not within any sandbox mount", runnerConfig.Command)
+	}
+
+	return nil
+}
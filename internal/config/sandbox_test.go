@@ -0,0 +1,124 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandboxMounts(t *testing.T) {
+	t.Run("default read-only mounts", func(t *testing.T) {
+		sandbox := &SandboxConfig{ReadWriteMounts: []string{"/data"}}
+
+		mounts := sandbox.Mounts()
+
+		assert.Len(t, mounts, len(defaultReadOnlyMounts)+1)
+		assert.Equal(t, Mount{Source: "/usr", Target: "/usr", ReadOnly: true, Optional: true}, mounts[len(mounts)-2])
+		assert.Equal(t, Mount{Source: "/data", Target: "/data"}, mounts[len(mounts)-1])
+	})
+
+	t.Run("configured mounts", func(t *testing.T) {
+		sandbox := &SandboxConfig{
+			ReadOnlyMounts:  []string{"/usr", "/opt/runner/:/runner"},
+			ReadWriteMounts: []string{"/var/lib/runner:/data"},
+		}
+
+		assert.Equal(t, []Mount{
+			{Source: "/usr", Target: "/usr", ReadOnly: true},
+			{Source: "/opt/runner", Target: "/runner", ReadOnly: true},
+			{Source: "/var/lib/runner", Target: "/data"},
+		}, sandbox.Mounts())
+	})
+}
+
+func TestSandboxValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		sandbox       SandboxConfig
+		runnerConfig  RunnerConfig
+		expectedError string
+	}{
+		{
+			name:         "valid sandbox",
+			sandbox:      SandboxConfig{ReadOnlyMounts: []string{"/usr", "/home/runner"}, PrivateTmp: true},
+			runnerConfig: RunnerConfig{WorkDir: "/home/runner", Command: "/usr/local/bin/node"},
+		},
+		{
+			name:         "relative command is not checked",
+			sandbox:      SandboxConfig{ReadOnlyMounts: []string{"/home/runner"}},
+			runnerConfig: RunnerConfig{WorkDir: "/home/runner", Command: "node"},
+		},
+		{
+			name:          "relative mount path",
+			sandbox:       SandboxConfig{ReadWriteMounts: []string{"data:/data"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/usr"},
+			expectedError: "sandbox mount data:/data: paths must be absolute",
+		},
+		{
+			name:          "mount over reserved path",
+			sandbox:       SandboxConfig{ReadOnlyMounts: []string{"/usr", "/proc"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/usr"},
+			expectedError: "sandbox mount /proc: cannot mount over /proc",
+		},
+		{
+			name:          "workdir outside mounts",
+			sandbox:       SandboxConfig{ReadOnlyMounts: []string{"/usr"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/home/runner", Command: "/usr/local/bin/node"},
+			expectedError: "sandbox: workdir /home/runner is not within any sandbox mount",
+		},
+		{
+			name:          "command outside mounts",
+			sandbox:       SandboxConfig{ReadOnlyMounts: []string{"/home/runner"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/home/runner", Command: "/usr/local/bin/node"},
+			expectedError: "sandbox: command /usr/local/bin/node is not within any sandbox mount",
+		},
+		{
+			name:          "path with shared prefix is not within mount",
+			sandbox:       SandboxConfig{ReadOnlyMounts: []string{"/usr", "/home/run"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/home/runner"},
+			expectedError: "sandbox: workdir /home/runner is not within any sandbox mount",
+		},
+		{
+			name:         "scratch dir in read-write workdir",
+			sandbox:      SandboxConfig{ReadOnlyMounts: []string{"/usr"}, ReadWriteMounts: []string{"/home/runner"}},
+			runnerConfig: RunnerConfig{WorkDir: "/home/runner", ScratchDir: true},
+		},
+		{
+			name:          "scratch dir in read-only workdir",
+			sandbox:       SandboxConfig{ReadOnlyMounts: []string{"/usr", "/home/runner"}},
+			runnerConfig:  RunnerConfig{WorkDir: "/home/runner", ScratchDir: true},
+			expectedError: "sandbox: workdir /home/runner must be within a read-write mount to use scratch-dir",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sandbox.validate(&tt.runnerConfig)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSandboxHostPath(t *testing.T) {
+	sandbox := &SandboxConfig{
+		ReadOnlyMounts:  []string{"/opt/runner:/app"},
+		ReadWriteMounts: []string{"/var/lib/runner:/app/data"},
+	}
+
+	hostPath, isWritable, ok := sandbox.HostPath("/app/data/scratch")
+	assert.True(t, ok)
+	assert.True(t, isWritable)
+	assert.Equal(t, "/var/lib/runner/scratch", hostPath)
+
+	hostPath, isWritable, ok = sandbox.HostPath("/app")
+	assert.True(t, ok)
+	assert.False(t, isWritable)
+	assert.Equal(t, "/opt/runner", hostPath)
+
+	_, _, ok = sandbox.HostPath("/home")
+	assert.False(t, ok)
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"task-runner-launcher/internal/seccomp"
+)
+
+// SeccompConfig holds the seccomp-bpf syscall filtering profile to apply to
+// each launch of a runner.
+type SeccompConfig struct {
+	// Path to a seccomp profile in the format of OCI seccomp profiles, as used by
+	// Docker. Default: the built-in profile, for `javascript` and `python` runners.
+	Profile string `json:"profile,omitempty"`
+
+	// Whether to only log the syscalls that the profile would deny, instead of
+	// denying them, so as to tune the profile before enforcing it.
+	LogOnly bool `json:"log-only,omitempty"`
+
+	// Profile read from `profile` or built in, resolved on load.
+	ParsedProfile *seccomp.Profile `json:"-"`
+}
+
+func (c *SeccompConfig) resolve(runnerType string) error {
+	if c.Profile == "" {
+		profile, ok := seccomp.DefaultProfile(runnerType)
+		if !ok {
+			return fmt.Errorf("seccomp profile is required, as there is no built-in profile for runner type %s", runnerType)
+		}
+		c.ParsedProfile = profile
+		return nil
+	}
+
+	profile, err := seccomp.LoadProfile(c.Profile)
+	if err != nil {
+		return fmt.Errorf("seccomp: %w", err)
+	}
+	c.ParsedProfile = profile
+
+	return nil
+}
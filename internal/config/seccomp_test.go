@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"task-runner-launcher/internal/seccomp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeccompResolve(t *testing.T) {
+	profilePath := filepath.Join(t.TempDir(), "profile.json")
+	require.NoError(t, os.WriteFile(profilePath, []byte(`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": []}`), 0o600))
+
+	tests := []struct {
+		name           string
+		config         SeccompConfig
+		runnerType     string
+		expectedAction seccomp.Action
+		expectedError  string
+	}{
+		{
+			name:           "built-in profile",
+			config:         SeccompConfig{},
+			runnerType:     "javascript",
+			expectedAction: seccomp.ActAllow,
+		},
+		{
+			name:           "configured profile",
+			config:         SeccompConfig{Profile: profilePath, LogOnly: true},
+			runnerType:     "rust",
+			expectedAction: seccomp.ActErrno,
+		},
+		{
+			name:          "no built-in profile",
+			config:        SeccompConfig{},
+			runnerType:    "rust",
+			expectedError: "seccomp profile is required, as there is no built-in profile for runner type rust",
+		},
+		{
+			name:          "missing profile",
+			config:        SeccompConfig{Profile: "/nonexistent/profile.json"},
+			runnerType:    "python",
+			expectedError: "seccomp: failed to read seccomp profile at /nonexistent/profile.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.resolve(tt.runnerType)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedAction, tt.config.ParsedProfile.DefaultAction)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// TLSConfig is the config for connections to the task broker over `https`,
+// applied to the readiness check, the grant token exchange and the websocket
+// handshake. Certs and keys are PEM-encoded, and like any env var may be read
+// from a file by appending `_FILE` to the env var name.
+type TLSConfig struct {
+	// CACert is the bundle of CA certs to verify the task broker's cert with,
+	// instead of the system's CA certs.
+	CACert string `env:"N8N_RUNNERS_TASK_BROKER_CA_CERT"`
+
+	// ClientCert and ClientKey are the cert and key that the launcher presents
+	// to the task broker for mutual TLS.
+	ClientCert string `env:"N8N_RUNNERS_TASK_BROKER_CLIENT_CERT"`
+	ClientKey  string `env:"N8N_RUNNERS_TASK_BROKER_CLIENT_KEY"`
+
+	// ServerName overrides the server name sent via SNI and verified against
+	// the task broker's cert, which defaults to the host of the task broker URI.
+	ServerName string `env:"N8N_RUNNERS_TASK_BROKER_SERVER_NAME"`
+}
+
+// ClientConfig returns the TLS config for connections to the task broker, or
+// nil if no TLS setting is set, in which case Go's defaults apply.
+func (c *TLSConfig) ClientConfig() (*tls.Config, error) {
+	if c == nil || *c == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return nil, errors.New("N8N_RUNNERS_TASK_BROKER_CA_CERT must contain at least one PEM-encoded cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return nil, errors.New("N8N_RUNNERS_TASK_BROKER_CLIENT_CERT and N8N_RUNNERS_TASK_BROKER_CLIENT_KEY must be set together")
+	}
+
+	if c.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(c.ClientCert), []byte(c.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("N8N_RUNNERS_TASK_BROKER_CLIENT_CERT and N8N_RUNNERS_TASK_BROKER_CLIENT_KEY must be a valid PEM-encoded cert and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSignedCert returns a PEM-encoded self-signed cert and its key.
+func selfSignedCert(t *testing.T) (certPEM, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+
+	return certPEM, keyPEM
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	certPEM, keyPEM := selfSignedCert(t)
+
+	tests := []struct {
+		name          string
+		config        *TLSConfig
+		expectNil     bool
+		expectedError string
+		check         func(*testing.T, *tls.Config)
+	}{
+		{
+			name:      "nil config",
+			config:    nil,
+			expectNil: true,
+		},
+		{
+			name:      "empty config",
+			config:    &TLSConfig{},
+			expectNil: true,
+		},
+		{
+			name:   "server name only",
+			config: &TLSConfig{ServerName: "broker.internal"},
+			check: func(t *testing.T, c *tls.Config) {
+				assert.Equal(t, "broker.internal", c.ServerName)
+				assert.Nil(t, c.RootCAs)
+				assert.Empty(t, c.Certificates)
+			},
+		},
+		{
+			name:   "CA cert",
+			config: &TLSConfig{CACert: certPEM},
+			check: func(t *testing.T, c *tls.Config) {
+				assert.NotNil(t, c.RootCAs)
+			},
+		},
+		{
+			name:   "client cert and key",
+			config: &TLSConfig{ClientCert: certPEM, ClientKey: keyPEM},
+			check: func(t *testing.T, c *tls.Config) {
+				assert.Len(t, c.Certificates, 1)
+			},
+		},
+		{
+			name:          "invalid CA cert",
+			config:        &TLSConfig{CACert: "not a cert"},
+			expectedError: "N8N_RUNNERS_TASK_BROKER_CA_CERT must contain at least one PEM-encoded cert",
+		},
+		{
+			name:          "client cert without key",
+			config:        &TLSConfig{ClientCert: certPEM},
+			expectedError: "N8N_RUNNERS_TASK_BROKER_CLIENT_CERT and N8N_RUNNERS_TASK_BROKER_CLIENT_KEY must be set together",
+		},
+		{
+			name:          "mismatched client cert and key",
+			config:        &TLSConfig{ClientCert: certPEM, ClientKey: certPEM},
+			expectedError: "must be a valid PEM-encoded cert and key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := tt.config.ClientConfig()
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.expectNil {
+				assert.Nil(t, tlsConfig)
+				return
+			}
+
+			require.NotNil(t, tlsConfig)
+			assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+			tt.check(t, tlsConfig)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"path"
 )
 
 func validateURL(urlStr string, urlName string) error {
@@ -22,3 +23,22 @@ func validateURL(urlStr string, urlName string) error {
 
 	return nil
 }
+
+// validateTaskBrokerURI validates a task broker URI, which may also be of the
+// form `unix:///path/to.sock` to connect over a unix domain socket.
+func validateTaskBrokerURI(uri string, uriName string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "unix" {
+		return validateURL(uri, uriName)
+	}
+
+	if u.Host != "" || !path.IsAbs(u.Path) {
+		return fmt.Errorf("%s must have an absolute socket path, e.g. unix:///path/to.sock", uriName)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s must have no query params or fragment", uriName)
+	}
+
+	return nil
+}
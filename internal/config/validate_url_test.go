@@ -62,3 +62,52 @@ func TestValidateURL(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTaskBrokerURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		errorMsg string
+	}{
+		{
+			name: "http URI",
+			uri:  "http://127.0.0.1:5679",
+		},
+		{
+			name: "unix socket URI",
+			uri:  "unix:///run/n8n/broker.sock",
+		},
+		{
+			name:     "unix socket URI with host",
+			uri:      "unix://run/n8n/broker.sock",
+			errorMsg: "must have an absolute socket path",
+		},
+		{
+			name:     "unix socket URI without path",
+			uri:      "unix://",
+			errorMsg: "must have an absolute socket path",
+		},
+		{
+			name:     "unix socket URI with query params",
+			uri:      "unix:///run/n8n/broker.sock?foo=bar",
+			errorMsg: "must have no query params or fragment",
+		},
+		{
+			name:     "unsupported scheme",
+			uri:      "ftp://127.0.0.1",
+			errorMsg: "must use http:// or https:// scheme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTaskBrokerURI(tt.uri, "N8N_RUNNERS_TASK_BROKER_URI")
+
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
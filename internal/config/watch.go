@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WatchFile checks the file at the given path at every interval, and sends on
+// the returned channel whenever the file's modification time or size changed
+// and then stayed the same for one more interval, so that a file still being
+// written is not reported until the write has settled, until the context is
+// cancelled. A file that is briefly missing, e.g. while being replaced, is
+// checked again at the next interval.
+func WatchFile(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	last, _ := os.Stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// change seen at the previous check, reported once it has settled
+		var pending os.FileInfo
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+
+			if last != nil && sameFileState(info, last) {
+				pending = nil
+				continue
+			}
+
+			if pending == nil || !sameFileState(info, pending) {
+				pending = info
+				continue
+			}
+
+			last = info
+			pending = nil
+
+			select {
+			case changes <- struct{}{}:
+			default: // a change is already pending
+			}
+		}
+	}()
+
+	return changes
+}
+
+func sameFileState(a, b os.FileInfo) bool {
+	return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchFile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+
+	changes := WatchFile(ctx, path, 30*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatal("Expected no change for an unchanged file")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, os.Remove(path))
+	time.Sleep(50 * time.Millisecond)
+
+	// write the file non-atomically, as an editor might
+	content := `{"task-runners": []}`
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(content[:10])
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = f.WriteString(content[10:])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	select {
+	case <-changes:
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data), "Expected the change to be reported once the write settled")
+	case <-time.After(time.Second):
+		t.Fatal("Expected a change for a rewritten file")
+	}
+
+	select {
+	case <-changes:
+		t.Fatal("Expected a single change per modification")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -32,6 +32,9 @@ const (
 	// EnvVarTaskTimeout is the env var for how long (in seconds) a task may run
 	// for before it is aborted.
 	EnvVarTaskTimeout = "N8N_RUNNERS_TASK_TIMEOUT"
+
+	// EnvVarScratchDir is the env var for the runner's per-launch scratch dir.
+	EnvVarScratchDir = "N8N_RUNNERS_SCRATCH_DIR"
 )
 
 // partitionByAllowlist divides the current env vars into those included in and
@@ -104,6 +107,7 @@ var requiredRuntimeEnvVars = []string{
 	EnvVarHealthCheckServerEnabled,
 	EnvVarGrantToken,
 	EnvVarHealthCheckServerPort,
+	EnvVarScratchDir,
 }
 
 // PrepareRunnerEnv prepares the environment variables to pass to the runner.
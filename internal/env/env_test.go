@@ -340,6 +340,42 @@ func TestPrepareRunnerEnv(t *testing.T) {
 				"PATH=/usr/bin",
 			},
 		},
+		{
+			name: "does not pass launcher's TLS settings to runner",
+			launcherConfig: &config.LauncherConfig{
+				BaseConfig: &config.BaseConfig{
+					AutoShutdownTimeout: "15",
+					TaskTimeout:         "60",
+					TaskBrokerURI:       "https://broker.internal:5679",
+					TLS: &config.TLSConfig{
+						CACert:     "ca-pem",
+						ClientCert: "cert-pem",
+						ClientKey:  "key-pem",
+						ServerName: "broker.internal",
+					},
+				},
+				RunnerConfigs: map[string]*config.RunnerConfig{
+					"javascript": {
+						AllowedEnv:            []string{"NODE_EXTRA_CA_CERTS"},
+						HealthCheckServerPort: "5681",
+					},
+				},
+			},
+			envSetup: map[string]string{
+				"PATH":                               "/usr/bin",
+				"NODE_EXTRA_CA_CERTS":                "/etc/n8n/ca.pem",
+				"N8N_RUNNERS_TASK_BROKER_CLIENT_KEY": "key-pem",
+			},
+			expected: []string{
+				"N8N_RUNNERS_AUTO_SHUTDOWN_TIMEOUT=15",
+				"N8N_RUNNERS_HEALTH_CHECK_SERVER_ENABLED=true",
+				"N8N_RUNNERS_HEALTH_CHECK_SERVER_PORT=5681",
+				"N8N_RUNNERS_TASK_BROKER_URI=https://broker.internal:5679",
+				"N8N_RUNNERS_TASK_TIMEOUT=60",
+				"NODE_EXTRA_CA_CERTS=/etc/n8n/ca.pem",
+				"PATH=/usr/bin",
+			},
+		},
 	}
 
 	for _, tt := range tests {
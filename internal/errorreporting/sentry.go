@@ -1,9 +1,13 @@
 package errorreporting
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"task-runner-launcher/internal/config"
 	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -12,6 +16,7 @@ import (
 var (
 	sentryInit  = sentry.Init
 	sentryFlush = sentry.Flush
+	sentryEvent = sentry.CaptureEvent
 	osExit      = os.Exit
 )
 
@@ -44,3 +49,51 @@ func Init(sentryCfg *config.SentryConfig) {
 func Close() {
 	sentryFlush(2 * time.Second)
 }
+
+// ReportRunnerExit reports a failed exit of a runner of the given type to Sentry.
+// Exits on launcher shutdown are not reported.
+func ReportRunnerExit(runnerType string, exit status.RunnerExit) {
+	if !exit.Failed() {
+		return
+	}
+
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelError
+	event.Message = fmt.Sprintf("Runner process exited unexpectedly: %v", exit)
+	event.Tags = map[string]string{
+		"runner_type":        runnerType,
+		"exit_code":          strconv.Itoa(exit.ExitCode),
+		"signal":             exit.Signal,
+		"killed_by_launcher": strconv.FormatBool(exit.KilledByLauncher),
+		"kill_reason":        string(exit.KillReason),
+		"oom_killed":         strconv.FormatBool(exit.OOMKilled),
+		"exceeded_rlimit":    exit.ExceededRlimit,
+	}
+	event.Extra = map[string]any{
+		"duration_seconds": exit.Duration.Seconds(),
+	}
+
+	sentryEvent(event)
+}
+
+// ReportIntegrityFailure reports to Sentry that a runner of the given type was
+// not launched on failing its integrity check.
+func ReportIntegrityFailure(runnerType string, err error) {
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelError
+	event.Message = fmt.Sprintf("Refused to launch runner on failed integrity check: %v", err)
+	event.Tags = map[string]string{
+		"runner_type": runnerType,
+	}
+
+	var integrityErr *config.IntegrityError
+	if errors.As(err, &integrityErr) {
+		event.Tags["path"] = integrityErr.Path
+		event.Extra = map[string]any{
+			"expected_sha256": integrityErr.Expected,
+			"actual_sha256":   integrityErr.Actual,
+		}
+	}
+
+	sentryEvent(event)
+}
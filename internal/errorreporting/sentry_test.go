@@ -2,7 +2,10 @@ package errorreporting
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/status"
 	"testing"
 	"time"
 
@@ -92,3 +95,82 @@ func TestClose(t *testing.T) {
 	Close()
 	assert.True(t, flushCalled, "expected sentry.Flush to be called")
 }
+
+func TestReportRunnerExit(t *testing.T) {
+	tests := []struct {
+		name         string
+		exit         status.RunnerExit
+		expectReport bool
+	}{
+		{
+			name:         "should not report exit on idle timeout",
+			exit:         status.RunnerExit{ExitCode: 0},
+			expectReport: false,
+		},
+		{
+			name:         "should not report exit on shutdown",
+			exit:         status.RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: status.KillReasonShutdown},
+			expectReport: false,
+		},
+		{
+			name:         "should report exit with error",
+			exit:         status.RunnerExit{ExitCode: 1},
+			expectReport: true,
+		},
+		{
+			name:         "should report OOM kill",
+			exit:         status.RunnerExit{ExitCode: -1, Signal: "SIGKILL", OOMKilled: true},
+			expectReport: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalSentryEvent := sentryEvent
+			defer func() { sentryEvent = originalSentryEvent }()
+
+			var reported *sentry.Event
+			sentryEvent = func(event *sentry.Event) *sentry.EventID {
+				reported = event
+				return nil
+			}
+
+			ReportRunnerExit("javascript", tt.exit)
+
+			if !tt.expectReport {
+				assert.Nil(t, reported)
+				return
+			}
+
+			if assert.NotNil(t, reported) {
+				assert.Equal(t, sentry.LevelError, reported.Level)
+				assert.Equal(t, "javascript", reported.Tags["runner_type"])
+				assert.Equal(t, strconv.Itoa(tt.exit.ExitCode), reported.Tags["exit_code"])
+				assert.Equal(t, tt.exit.Signal, reported.Tags["signal"])
+				assert.Equal(t, strconv.FormatBool(tt.exit.OOMKilled), reported.Tags["oom_killed"])
+			}
+		})
+	}
+}
+
+func TestReportIntegrityFailure(t *testing.T) {
+	originalSentryEvent := sentryEvent
+	defer func() { sentryEvent = originalSentryEvent }()
+
+	var reported *sentry.Event
+	sentryEvent = func(event *sentry.Event) *sentry.EventID {
+		reported = event
+		return nil
+	}
+
+	err := fmt.Errorf("command: %w", &config.IntegrityError{Path: "/usr/bin/node", Expected: "aa", Actual: "bb"})
+	ReportIntegrityFailure("javascript", err)
+
+	if assert.NotNil(t, reported) {
+		assert.Equal(t, sentry.LevelError, reported.Level)
+		assert.Equal(t, "javascript", reported.Tags["runner_type"])
+		assert.Equal(t, "/usr/bin/node", reported.Tags["path"])
+		assert.Equal(t, "aa", reported.Extra["expected_sha256"])
+		assert.Equal(t, "bb", reported.Extra["actual_sha256"])
+	}
+}
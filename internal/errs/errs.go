@@ -14,4 +14,8 @@ var (
 
 	// ErrNegativeAutoShutdownTimeout is returned when the auto shutdown timeout is a negative integer.
 	ErrNegativeAutoShutdownTimeout = errors.New("negative auto-shutdown timeout - N8N_RUNNERS_AUTO_SHUTDOWN_TIMEOUT must be >= 0")
+
+	// ErrUnknownRunnerType is returned when an action targets a runner type that
+	// the launcher was not started with.
+	ErrUnknownRunnerType = errors.New("unknown runner type")
 )
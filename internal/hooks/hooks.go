@@ -0,0 +1,110 @@
+// Package hooks runs the commands configured to run around each launch of a
+// runner, such as `pre-launch` and `post-exit`.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/process"
+	"time"
+)
+
+const (
+	// PreLaunch is the name of the hook run before each launch of a runner.
+	PreLaunch = "pre-launch"
+
+	// PostExit is the name of the hook run after each launch of a runner has exited.
+	PostExit = "post-exit"
+)
+
+const (
+	// EnvVarHook is the env var for the name of the running hook.
+	EnvVarHook = "N8N_RUNNERS_HOOK"
+
+	// EnvVarRunnerType is the env var for the type of the launched runner.
+	EnvVarRunnerType = "N8N_RUNNERS_RUNNER_TYPE"
+
+	// EnvVarLaunchID is the env var for the ID of the launch, shared by the
+	// `pre-launch` and `post-exit` hooks of the same launch.
+	EnvVarLaunchID = "N8N_RUNNERS_LAUNCH_ID"
+
+	// EnvVarExitCode is the env var for the runner's exit code, or -1 if the
+	// runner was terminated by a signal. Set for `post-exit` only.
+	EnvVarExitCode = "N8N_RUNNERS_EXIT_CODE"
+
+	// EnvVarExitSignal is the env var for the name of the signal that terminated
+	// the runner, if any. Set for `post-exit` only.
+	EnvVarExitSignal = "N8N_RUNNERS_EXIT_SIGNAL"
+
+	// EnvVarKillReason is the env var for why the launcher terminated the runner,
+	// if it did. Set for `post-exit` only.
+	EnvVarKillReason = "N8N_RUNNERS_KILL_REASON"
+)
+
+// terminationGracePeriod is how long a hook that timed out may take to exit
+// after receiving SIGTERM, before it is killed.
+var terminationGracePeriod = 5 * time.Second
+
+// Run runs the hook in the given dir with the given env vars, relaying its
+// output to the given writers, until the hook's process tree has exited. If
+// the hook exceeds its timeout or the context is cancelled, the hook is
+// terminated. Returns an error if the hook could not be run, did not exit
+// successfully, or was terminated.
+func Run(
+	ctx context.Context,
+	hook *config.HookConfig,
+	dir string,
+	env []string,
+	stdout, stderr io.Writer,
+) error {
+	cmd := process.Command(hook.Command, hook.Args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	group, err := process.Start(cmd, stdout, stderr)
+	if err != nil {
+		return fmt.Errorf("failed to start hook: %w", err)
+	}
+
+	timeout := hook.TimeoutDuration()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	exited := make(chan struct{})
+	var terminateErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			terminateErr = group.Terminate(ctx.Err().Error(), terminationGracePeriod)
+		case <-exited:
+		}
+	}()
+
+	err = group.Wait()
+	close(exited)
+	wg.Wait()
+
+	if group.TerminateReason() != "" {
+		switch {
+		case terminateErr != nil:
+			return fmt.Errorf("failed to terminate hook: %w", terminateErr)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return fmt.Errorf("hook timed out after %v", timeout)
+		default:
+			return fmt.Errorf("hook was cancelled: %w", ctx.Err())
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("hook failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"task-runner-launcher/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	terminationGracePeriod = 100 * time.Millisecond
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	hook := &config.HookConfig{Command: "sh", Args: []string{"-c", `echo "$N8N_RUNNERS_HOOK $(pwd)"; echo oops >&2`}}
+
+	var stdout, stderr bytes.Buffer
+	err := Run(context.Background(), hook, dir, []string{EnvVarHook + "=" + PreLaunch}, &stdout, &stderr)
+
+	require.NoError(t, err)
+	assert.Equal(t, "pre-launch "+dir+"\n", stdout.String())
+	assert.Equal(t, "oops\n", stderr.String())
+}
+
+func TestRunFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		hook          *config.HookConfig
+		ctxTimeout    time.Duration
+		expectedError string
+	}{
+		{
+			name:          "nonexistent command",
+			hook:          &config.HookConfig{Command: "/nonexistent/hook"},
+			expectedError: "failed to start hook",
+		},
+		{
+			name:          "exit with error",
+			hook:          &config.HookConfig{Command: "sh", Args: []string{"-c", "exit 2"}},
+			expectedError: "hook failed: exit status 2",
+		},
+		{
+			name:          "timeout",
+			hook:          &config.HookConfig{Command: "sh", Args: []string{"-c", "trap '' TERM; sleep 10"}, Timeout: 1},
+			expectedError: "hook timed out after 1s",
+		},
+		{
+			name:          "cancellation",
+			hook:          &config.HookConfig{Command: "sleep", Args: []string{"10"}},
+			ctxTimeout:    50 * time.Millisecond,
+			expectedError: "hook was cancelled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				time.AfterFunc(tt.ctxTimeout, cancel)
+				defer cancel()
+			}
+
+			startedAt := time.Now()
+			err := Run(ctx, tt.hook, t.TempDir(), nil, io.Discard, io.Discard)
+
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.Less(t, time.Since(startedAt), 5*time.Second, "Expected hook to be terminated")
+		})
+	}
+}
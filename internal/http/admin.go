@@ -0,0 +1,126 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
+)
+
+const adminPathPrefix = "/admin/runners"
+
+// RunnerController controls the launch cycles of runner types on behalf of the
+// admin API. Every method returns errs.ErrUnknownRunnerType for a runner type
+// the launcher was not started with.
+type RunnerController interface {
+	// Restart terminates the runners of the runner type and launches it again.
+	Restart(runnerType string) error
+
+	// Drain stops launching runners of the runner type and terminates its
+	// runners once they finish their current task, until resumed.
+	Drain(runnerType string) error
+
+	// Pause stops launching runners of the runner type, until resumed.
+	Pause(runnerType string) error
+
+	// Resume resumes launching runners of a paused or drained runner type.
+	Resume(runnerType string) error
+}
+
+type adminRunnersResponse struct {
+	Runners map[string]status.Runner `json:"runners"`
+}
+
+type adminActionResponse struct {
+	RunnerType string `json:"runnerType"`
+	Action     string `json:"action"`
+}
+
+type adminErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// registerAdminHandlers registers the admin API on the given mux, with every
+// request required to carry the given token as a bearer token.
+func registerAdminHandlers(mux *http.ServeMux, token string, registry *status.Registry, controller RunnerController) {
+	mux.Handle("GET "+adminPathPrefix, requireToken(token, handleListRunners(registry)))
+	mux.Handle("GET "+adminPathPrefix+"/{runnerType}", requireToken(token, handleGetRunner(registry)))
+	mux.Handle("POST "+adminPathPrefix+"/{runnerType}/{action}", requireToken(token, handleRunnerAction(controller)))
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="launcher-admin"`)
+			writeJSON(w, http.StatusUnauthorized, adminErrorResponse{Error: "missing or invalid admin token"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handleListRunners(registry *status.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, adminRunnersResponse{Runners: registry.Runners()})
+	}
+}
+
+func handleGetRunner(registry *status.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runner, ok := registry.Runners()[r.PathValue("runnerType")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, adminErrorResponse{Error: errs.ErrUnknownRunnerType.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, runner)
+	}
+}
+
+func handleRunnerAction(controller RunnerController) http.HandlerFunc {
+	actions := map[string]func(string) error{
+		"restart": controller.Restart,
+		"drain":   controller.Drain,
+		"pause":   controller.Pause,
+		"resume":  controller.Resume,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		runnerType := r.PathValue("runnerType")
+		action := r.PathValue("action")
+
+		fn, ok := actions[action]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, adminErrorResponse{Error: "unknown action " + action})
+			return
+		}
+
+		if err := fn(runnerType); err != nil {
+			if errors.Is(err, errs.ErrUnknownRunnerType) {
+				writeJSON(w, http.StatusNotFound, adminErrorResponse{Error: err.Error()})
+				return
+			}
+			logs.Errorf("Failed to %s runner %s via admin API: %v", action, runnerType, err)
+			writeJSON(w, http.StatusInternalServerError, adminErrorResponse{Error: err.Error()})
+			return
+		}
+
+		logs.Infof("Accepted %s of runner %s via admin API", action, runnerType)
+		writeJSON(w, http.StatusAccepted, adminActionResponse{RunnerType: runnerType, Action: action})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logs.Errorf("Failed to encode admin API response: %v", err)
+	}
+}
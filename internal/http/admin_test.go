@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/status"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "secret"
+
+type fakeController struct {
+	calls []string
+}
+
+func (c *fakeController) Restart(runnerType string) error { return c.call("restart", runnerType) }
+func (c *fakeController) Drain(runnerType string) error   { return c.call("drain", runnerType) }
+func (c *fakeController) Pause(runnerType string) error   { return c.call("pause", runnerType) }
+func (c *fakeController) Resume(runnerType string) error  { return c.call("resume", runnerType) }
+
+func (c *fakeController) call(action, runnerType string) error {
+	switch runnerType {
+	case "javascript":
+		c.calls = append(c.calls, action+" "+runnerType)
+		return nil
+	case "broken":
+		return errors.New("something broke")
+	default:
+		return errs.ErrUnknownRunnerType
+	}
+}
+
+func newAdminTestServer(controller RunnerController) http.Handler {
+	registry := status.NewRegistry()
+	registry.Update("javascript", func(runner *status.Runner) {
+		runner.Instances = []status.Instance{{State: status.StateRunning, PID: 1234}}
+	})
+
+	return newHealthCheckServer("5680", registry, testAdminToken, controller).Handler
+}
+
+func TestAdminAPI(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedCalls  []string
+	}{
+		{
+			name:           "list runners",
+			method:         http.MethodGet,
+			path:           "/admin/runners",
+			token:          testAdminToken,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "get runner",
+			method:         http.MethodGet,
+			path:           "/admin/runners/javascript",
+			token:          testAdminToken,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "get unknown runner",
+			method:         http.MethodGet,
+			path:           "/admin/runners/ruby",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing token",
+			method:         http.MethodGet,
+			path:           "/admin/runners",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid token",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/restart",
+			token:          "wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "restart",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/restart",
+			token:          testAdminToken,
+			expectedStatus: http.StatusAccepted,
+			expectedCalls:  []string{"restart javascript"},
+		},
+		{
+			name:           "drain",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/drain",
+			token:          testAdminToken,
+			expectedStatus: http.StatusAccepted,
+			expectedCalls:  []string{"drain javascript"},
+		},
+		{
+			name:           "pause",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/pause",
+			token:          testAdminToken,
+			expectedStatus: http.StatusAccepted,
+			expectedCalls:  []string{"pause javascript"},
+		},
+		{
+			name:           "resume",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/resume",
+			token:          testAdminToken,
+			expectedStatus: http.StatusAccepted,
+			expectedCalls:  []string{"resume javascript"},
+		},
+		{
+			name:           "unknown action",
+			method:         http.MethodPost,
+			path:           "/admin/runners/javascript/explode",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "action on unknown runner",
+			method:         http.MethodPost,
+			path:           "/admin/runners/ruby/restart",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "failed action",
+			method:         http.MethodPost,
+			path:           "/admin/runners/broken/restart",
+			token:          testAdminToken,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "action via GET",
+			method:         http.MethodGet,
+			path:           "/admin/runners/javascript/restart",
+			token:          testAdminToken,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &fakeController{}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+
+			newAdminTestServer(controller).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "unexpected status code")
+			assert.Equal(t, tt.expectedCalls, controller.calls)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAdminAPIReportsRunners(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/runners", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+
+	newAdminTestServer(&fakeController{}).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response adminRunnersResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	runner := response.Runners["javascript"]
+	assert.Equal(t, status.StateRunning, runner.State)
+	require.Len(t, runner.Instances, 1)
+	assert.Equal(t, 1234, runner.Instances[0].PID)
+}
+
+func TestAdminAPIDisabledWithoutToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/runners", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+
+	newHealthCheckServer("5680", status.NewRegistry(), "", nil).Handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
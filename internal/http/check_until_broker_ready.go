@@ -1,21 +1,24 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"task-runner-launcher/internal/broker"
 	"task-runner-launcher/internal/logs"
 	"task-runner-launcher/internal/retry"
 	"time"
 )
 
-func sendHealthRequest(taskBrokerURI string) (*http.Response, error) {
-	url := fmt.Sprintf("%s/healthz", taskBrokerURI)
+func sendHealthRequest(ctx context.Context, transport *broker.Transport, taskBrokerURI string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/healthz", broker.HTTPURI(taskBrokerURI))
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
+	client := transport.HTTPClient(5 * time.Second)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -23,31 +26,82 @@ func sendHealthRequest(taskBrokerURI string) (*http.Response, error) {
 	return client.Do(req)
 }
 
-// CheckUntilBrokerReady checks forever until the task broker is ready, i.e.
-// In case of long-running migrations, readiness may take a long time.
-// Returns nil when ready.
-func CheckUntilBrokerReady(taskBrokerURI string, logger *logs.Logger) error {
+// CheckBrokerReady checks once whether the task broker is ready.
+func CheckBrokerReady(ctx context.Context, transport *broker.Transport, taskBrokerURI string) error {
+	resp, err := sendHealthRequest(ctx, transport, taskBrokerURI)
+	if err != nil {
+		return fmt.Errorf("task broker readiness check failed with error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("task broker readiness check failed with status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// CheckUntilBrokerReady checks forever until any of the given task brokers is
+// ready. In case of long-running migrations, readiness may take a long time.
+// Each attempt checks all task brokers at once. Returns the task brokers found
+// ready, in the given order, or an error if the context is cancelled first.
+func CheckUntilBrokerReady(ctx context.Context, endpoints []*broker.Endpoint, logger *logs.Logger) ([]*broker.Endpoint, error) {
 	logger.Info("Waiting for task broker to be ready...")
 
-	healthCheck := func() (string, error) {
-		resp, err := sendHealthRequest(taskBrokerURI)
-		if err != nil {
-			return "", fmt.Errorf("task broker readiness check failed with error: %w", err)
-		}
-		defer resp.Body.Close()
+	healthCheck := func() ([]*broker.Endpoint, error) {
+		return checkBrokersReady(ctx, endpoints)
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("task broker readiness check failed with status code: %d", resp.StatusCode)
-		}
+	ready, err := retry.UnlimitedRetry(ctx, "readiness-check", healthCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("Task broker(s) ready: %s", uris(ready))
+
+	return ready, nil
+}
+
+// checkBrokersReady checks at once whether each of the given task brokers is
+// ready, and returns those that are, in the given order, or an error if none is.
+func checkBrokersReady(ctx context.Context, endpoints []*broker.Endpoint) ([]*broker.Endpoint, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no task broker to check")
+	}
+
+	errs := make([]error, len(endpoints))
 
-		return "", nil
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := CheckBrokerReady(ctx, endpoint.Transport, endpoint.URI); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", endpoint.URI, err)
+			}
+		}()
 	}
+	wg.Wait()
 
-	if _, err := retry.UnlimitedRetry("readiness-check", healthCheck); err != nil {
-		return err
+	var ready []*broker.Endpoint
+	for i, endpoint := range endpoints {
+		if errs[i] == nil {
+			ready = append(ready, endpoint)
+		}
 	}
 
-	logger.Debug("Task broker is ready")
+	if len(ready) == 0 {
+		return nil, errors.Join(errs...)
+	}
 
-	return nil
+	return ready, nil
+}
+
+func uris(endpoints []*broker.Endpoint) string {
+	uris := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		uris[i] = endpoint.URI
+	}
+
+	return strings.Join(uris, ", ")
 }
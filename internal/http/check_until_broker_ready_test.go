@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"task-runner-launcher/internal/broker"
 	"task-runner-launcher/internal/logs"
 	"testing"
 	"time"
@@ -12,6 +13,15 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func endpointsAt(uris ...string) []*broker.Endpoint {
+	endpoints := make([]*broker.Endpoint, len(uris))
+	for i, uri := range uris {
+		endpoints[i] = &broker.Endpoint{URI: uri}
+	}
+
+	return endpoints
+}
+
 func TestCheckUntilBrokerReadyHappyPath(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -45,7 +55,8 @@ func TestCheckUntilBrokerReadyHappyPath(t *testing.T) {
 			done := make(chan error)
 			go func() {
 				logger := logs.NewLogger(logs.InfoLevel, "")
-				done <- CheckUntilBrokerReady(srv.URL, logger)
+				_, err := CheckUntilBrokerReady(ctx, endpointsAt(srv.URL), logger)
+				done <- err
 			}()
 
 			select {
@@ -100,7 +111,8 @@ func TestCheckUntilBrokerReadyErrors(t *testing.T) {
 			brokerUnexpectedlyReady := make(chan error)
 			go func() {
 				logger := logs.NewLogger(logs.InfoLevel, "")
-				brokerUnexpectedlyReady <- CheckUntilBrokerReady(srv.URL, logger)
+				_, err := CheckUntilBrokerReady(context.Background(), endpointsAt(srv.URL), logger)
+				brokerUnexpectedlyReady <- err
 			}()
 
 			select {
@@ -113,6 +125,80 @@ func TestCheckUntilBrokerReadyErrors(t *testing.T) {
 	}
 }
 
+func TestCheckUntilBrokerReadyCancellation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		logger := logs.NewLogger(logs.InfoLevel, "")
+		_, err := CheckUntilBrokerReady(ctx, endpointsAt(srv.URL), logger)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled, "Expected cancellation error")
+	case <-time.After(time.Second):
+		t.Error("Expected readiness check to stop on cancellation")
+	}
+}
+
+func TestCheckUntilBrokerReadyMultipleBrokers(t *testing.T) {
+	ready := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	}
+	notReady := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer notReady.Close()
+	first, second := ready(), ready()
+	defer first.Close()
+	defer second.Close()
+
+	tests := []struct {
+		name     string
+		uris     []string
+		expected []string
+	}{
+		{
+			name:     "all ready",
+			uris:     []string{second.URL, first.URL},
+			expected: []string{second.URL, first.URL},
+		},
+		{
+			name:     "some ready",
+			uris:     []string{notReady.URL, first.URL},
+			expected: []string{first.URL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			endpoints, err := CheckUntilBrokerReady(ctx, endpointsAt(tt.uris...), logs.NewLogger(logs.InfoLevel, ""))
+			require.NoError(t, err)
+
+			uris := make([]string, len(endpoints))
+			for i, endpoint := range endpoints {
+				uris[i] = endpoint.URI
+			}
+			assert.Equal(t, tt.expected, uris)
+		})
+	}
+}
+
 func TestSendReadinessRequest(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -145,7 +231,7 @@ func TestSendReadinessRequest(t *testing.T) {
 			}))
 			defer srv.Close()
 
-			resp, err := sendHealthRequest(srv.URL)
+			resp, err := sendHealthRequest(context.Background(), nil, srv.URL)
 
 			if !tt.expectedError {
 				require.NoError(t, err, "Unexpected error making request")
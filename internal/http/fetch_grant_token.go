@@ -2,20 +2,27 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"task-runner-launcher/internal/broker"
+	"task-runner-launcher/internal/errs"
 	"task-runner-launcher/internal/retry"
+	"time"
 )
 
+// grantTokenFetchTimeout bounds a single attempt to fetch a grant token.
+const grantTokenFetchTimeout = 5 * time.Second
+
 type grantTokenResponse struct {
 	Data struct {
 		Token string `json:"token"`
 	} `json:"data"`
 }
 
-func sendGrantTokenRequest(taskBrokerServerURI, authToken string) (string, error) {
-	url := fmt.Sprintf("%s/runners/auth", taskBrokerServerURI)
+func sendGrantTokenRequest(ctx context.Context, transport *broker.Transport, taskBrokerServerURI, authToken string) (string, error) {
+	url := fmt.Sprintf("%s/runners/auth", broker.HTTPURI(taskBrokerServerURI))
 
 	payload := map[string]string{"token": authToken}
 	payloadBytes, err := json.Marshal(payload)
@@ -23,16 +30,16 @@ func sendGrantTokenRequest(taskBrokerServerURI, authToken string) (string, error
 		return "", fmt.Errorf("failed to marshal grant token request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return "", fmt.Errorf("failed to create grant token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := transport.HTTPClient(0)
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %w", errs.ErrServerDown, err)
 	}
 	defer resp.Body.Close()
 
@@ -50,17 +57,18 @@ func sendGrantTokenRequest(taskBrokerServerURI, authToken string) (string, error
 
 // FetchGrantToken exchanges the launcher's auth token for a single-use grant
 // token from the task broker. In case the task broker is temporarily
-// unavailable, this exchange is retried a limited number of times.
-func FetchGrantToken(taskBrokerServerURI, authToken string) (string, error) {
+// unavailable, this exchange is retried a limited number of times, after which
+// the error wraps errs.ErrServerDown if the task broker was unreachable.
+func FetchGrantToken(ctx context.Context, transport *broker.Transport, taskBrokerServerURI, authToken string) (string, error) {
 	grantTokenFetch := func() (string, error) {
-		token, err := sendGrantTokenRequest(taskBrokerServerURI, authToken)
+		token, err := sendGrantTokenRequest(ctx, transport, taskBrokerServerURI, authToken)
 		if err != nil {
 			return "", fmt.Errorf("failed to fetch grant token: %w", err)
 		}
 		return token, nil
 	}
 
-	token, err := retry.LimitedRetry("grant-token-fetch", grantTokenFetch)
+	token, err := retry.LimitedRetry(ctx, "grant-token-fetch", grantTokenFetch)
 
 	if err != nil {
 		return "", fmt.Errorf("exhausted retries to fetch grant token: %w", err)
@@ -68,3 +76,19 @@ func FetchGrantToken(taskBrokerServerURI, authToken string) (string, error) {
 
 	return token, nil
 }
+
+// FetchGrantTokenOnce exchanges the launcher's auth token for a single-use grant
+// token from the task broker in a single short attempt, for callers that fail
+// over to another task broker instead of retrying. The error wraps
+// errs.ErrServerDown if the task broker was unreachable.
+func FetchGrantTokenOnce(ctx context.Context, transport *broker.Transport, taskBrokerServerURI, authToken string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, grantTokenFetchTimeout)
+	defer cancel()
+
+	token, err := sendGrantTokenRequest(ctx, transport, taskBrokerServerURI, authToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch grant token: %w", err)
+	}
+
+	return token, nil
+}
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"task-runner-launcher/internal/errs"
 	"task-runner-launcher/internal/retry"
 	"testing"
 	"time"
@@ -93,7 +95,7 @@ func TestFetchGrantToken(t *testing.T) {
 			}))
 			defer srv.Close()
 
-			token, err := FetchGrantToken(srv.URL, tt.authToken)
+			token, err := FetchGrantToken(context.Background(), nil, srv.URL, tt.authToken)
 
 			if tt.wantErr {
 				assert.Error(t, err, "Expected an error")
@@ -108,7 +110,7 @@ func TestFetchGrantToken(t *testing.T) {
 }
 
 func TestFetchGrantTokenInvalidURL(t *testing.T) {
-	token, err := FetchGrantToken("not-a-valid-url", "test-token")
+	token, err := FetchGrantToken(context.Background(), nil, "not-a-valid-url", "test-token")
 
 	assert.Error(t, err, "Expected error for invalid URL")
 	assert.Empty(t, token, "Token should be empty for invalid URL")
@@ -133,7 +135,7 @@ func TestFetchGrantTokenRetry(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	token, err := FetchGrantToken(srv.URL, "test-token")
+	token, err := FetchGrantToken(context.Background(), nil, srv.URL, "test-token")
 
 	assert.NoError(t, err, "Unexpected error after retry")
 	assert.NotEmpty(t, token, "Expected non-empty token after retry")
@@ -143,9 +145,32 @@ func TestFetchGrantTokenRetry(t *testing.T) {
 func TestFetchGrantTokenConnectionFailure(t *testing.T) {
 	invalidServerURL := "http://localhost:1"
 
-	token, err := FetchGrantToken(invalidServerURL, "test-token")
+	token, err := FetchGrantToken(context.Background(), nil, invalidServerURL, "test-token")
 
 	assert.Error(t, err, "Expected error for connection failure")
 	assert.Contains(t, err.Error(), "connection refused", "Unexpected error message")
+	assert.ErrorIs(t, err, errs.ErrServerDown, "Expected connection failure to mean the task broker is down")
+	assert.Empty(t, token, "Token should be empty for failed connection")
+}
+
+func TestFetchGrantTokenOnce(t *testing.T) {
+	tryCount := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		tryCount++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	token, err := FetchGrantTokenOnce(context.Background(), nil, srv.URL, "test-token")
+
+	assert.ErrorContains(t, err, "status code 500")
+	assert.Empty(t, token, "Token should be empty on error")
+	assert.Equal(t, 1, tryCount, "Expected a single attempt")
+}
+
+func TestFetchGrantTokenOnceConnectionFailure(t *testing.T) {
+	token, err := FetchGrantTokenOnce(context.Background(), nil, "http://localhost:1", "test-token")
+
+	assert.ErrorIs(t, err, errs.ErrServerDown, "Expected connection failure to mean the task broker is down")
 	assert.Empty(t, token, "Token should be empty for failed connection")
 }
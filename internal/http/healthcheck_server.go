@@ -2,10 +2,12 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/status"
 	"time"
 )
 
@@ -16,12 +18,16 @@ const (
 )
 
 // InitHealthCheckServer creates and starts the launcher's health check server
-// exposing `/healthz` at the given port, running in a goroutine.
-func InitHealthCheckServer(port string) {
-	srv := newHealthCheckServer(port)
+// exposing `/healthz` at the given port, running in a goroutine, reporting the
+// status of runner types from the given registry. If an admin token is given,
+// the server also exposes the admin API under `/admin/runners`, controlling
+// runner types via the given controller. The returned server is to be shut
+// down by the caller on launcher shutdown.
+func InitHealthCheckServer(port string, registry *status.Registry, adminToken string, controller RunnerController) *http.Server {
+	srv := newHealthCheckServer(port, registry, adminToken, controller)
 	logs.Infof("Starting launcher's health check server at port %s", port)
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errMsg := "Health check server failed to start"
 			if opErr, ok := err.(*net.OpError); ok && opErr.Op == "listen" {
 				errMsg = fmt.Sprintf("%s: Port %s is already in use", errMsg, srv.Addr)
@@ -32,11 +38,16 @@ func InitHealthCheckServer(port string) {
 			return
 		}
 	}()
+
+	return srv
 }
 
-func newHealthCheckServer(port string) *http.Server {
+func newHealthCheckServer(port string, registry *status.Registry, adminToken string, controller RunnerController) *http.Server {
 	mux := http.NewServeMux()
-	mux.HandleFunc(healthCheckPath, handleHealthCheck)
+	mux.HandleFunc(healthCheckPath, handleHealthCheck(registry))
+	if adminToken != "" {
+		registerAdminHandlers(mux, adminToken, registry, controller)
+	}
 
 	return &http.Server{
 		Addr:         fmt.Sprintf(":%s", port),
@@ -46,21 +57,61 @@ func newHealthCheckServer(port string) *http.Server {
 	}
 }
 
-func handleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
+// healthCheckResponse is the response of the launcher's health check server.
+// The status is `degraded` while the circuit of any runner type is not closed
+// or any runner type failed its integrity check, in which case the launcher is
+// still alive, so the response is still a 200.
+type healthCheckResponse struct {
+	Status  string                       `json:"status"`
+	Runners map[string]healthCheckRunner `json:"runners"`
+}
+
+// healthCheckRunner is the status of a runner type as reported by the health
+// check server. As the health check server is unauthenticated, the PIDs and
+// uptimes of the runner type's instances are left to the admin API.
+type healthCheckRunner struct {
+	State               status.State        `json:"state"`
+	Broker              string              `json:"broker,omitempty"`
+	Circuit             status.CircuitState `json:"circuit"`
+	ConsecutiveFailures int                 `json:"consecutiveFailures"`
+	CircuitOpenUntil    *time.Time          `json:"circuitOpenUntil,omitempty"`
+	LastExit            *status.RunnerExit  `json:"lastExit,omitempty"`
+	IntegrityError      string              `json:"integrityError,omitempty"`
+}
+
+func newHealthCheckRunner(runner status.Runner) healthCheckRunner {
+	return healthCheckRunner{
+		State:               runner.State,
+		Broker:              runner.Broker,
+		Circuit:             runner.Circuit,
+		ConsecutiveFailures: runner.ConsecutiveFailures,
+		CircuitOpenUntil:    runner.CircuitOpenUntil,
+		LastExit:            runner.LastExit,
+		IntegrityError:      runner.IntegrityError,
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
+func handleHealthCheck(registry *status.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 
-	res := struct {
-		Status string `json:"status"`
-	}{Status: "ok"}
+		w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(res); err != nil {
-		logs.Errorf("Failed to encode health check response: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		res := healthCheckResponse{Status: "ok", Runners: make(map[string]healthCheckRunner)}
+		for runnerType, runner := range registry.Runners() {
+			res.Runners[runnerType] = newHealthCheckRunner(runner)
+		}
+		if registry.IsDegraded() {
+			res.Status = "degraded"
+		}
+
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			logs.Errorf("Failed to encode health check response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"task-runner-launcher/internal/status"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,7 +38,7 @@ func TestHealthCheckHandler(t *testing.T) {
 			req := httptest.NewRequest(tt.method, "/healthz", nil)
 			w := httptest.NewRecorder()
 
-			handleHealthCheck(w, req)
+			handleHealthCheck(status.NewRegistry())(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code, "unexpected status code")
 
@@ -56,13 +57,68 @@ func TestHealthCheckHandler(t *testing.T) {
 	}
 }
 
+func TestHealthCheckHandlerReportsRunners(t *testing.T) {
+	registry := status.NewRegistry()
+	registry.Update("javascript", func(runner *status.Runner) {})
+	registry.UpdateInstance("python", 0, func(instance *status.Instance) {
+		instance.State = status.StateRunning
+		instance.PID = 1234
+	})
+	registry.Update("python", func(runner *status.Runner) {
+		runner.Circuit = status.CircuitOpen
+		runner.ConsecutiveFailures = 5
+		runner.IntegrityError = "command: sha256 mismatch"
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+
+	handleHealthCheck(registry)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "degraded launcher should still be alive")
+
+	var response healthCheckResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+
+	assert.Equal(t, "degraded", response.Status)
+	assert.Equal(t, status.StateIdle, response.Runners["javascript"].State)
+	assert.Equal(t, status.CircuitClosed, response.Runners["javascript"].Circuit)
+	assert.Equal(t, status.StateRunning, response.Runners["python"].State)
+	assert.Equal(t, status.CircuitOpen, response.Runners["python"].Circuit)
+	assert.Equal(t, 5, response.Runners["python"].ConsecutiveFailures)
+	assert.Equal(t, "command: sha256 mismatch", response.Runners["python"].IntegrityError)
+}
+
+func TestHealthCheckHandlerOmitsInstances(t *testing.T) {
+	registry := status.NewRegistry()
+	registry.UpdateInstance("python", 0, func(instance *status.Instance) {
+		instance.State = status.StateRunning
+		instance.PID = 1234
+		instance.Broker = "http://worker-1:5679"
+	})
+	registry.Update("python", func(runner *status.Runner) {
+		runner.Broker = "http://worker-1:5679"
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+
+	handleHealthCheck(registry)(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `"broker":"http://worker-1:5679"`)
+	for _, detail := range []string{"1234", "instances", "pid", "uptimeSeconds", "paused"} {
+		assert.NotContains(t, body, detail, "unauthenticated health check should not expose instance details")
+	}
+}
+
 func TestHealthCheckHandlerEncodingError(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 
 	failingWriter := &failingWriter{
 		headers: http.Header{},
 	}
-	handleHealthCheck(failingWriter, req)
+	handleHealthCheck(status.NewRegistry())(failingWriter, req)
 
 	assert.Equal(t, http.StatusInternalServerError, failingWriter.statusCode,
 		"unexpected status code for encoding error")
@@ -86,7 +142,7 @@ func (w *failingWriter) WriteHeader(statusCode int) {
 }
 
 func TestNewHealthCheckServer(t *testing.T) {
-	server := newHealthCheckServer("5680")
+	server := newHealthCheckServer("5680", status.NewRegistry(), "", nil)
 
 	require.NotNil(t, server, "server should not be nil")
 
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os/exec"
 	"sync"
 	"task-runner-launcher/internal/logs"
 	"time"
@@ -28,6 +27,17 @@ var (
 	initialDelay = 3 * time.Second
 )
 
+// runnerHealthCheckTransport is the transport for health check requests to
+// runners, which never go through a proxy, unlike requests to the task broker.
+var runnerHealthCheckTransport = newDirectTransport()
+
+func newDirectTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+
+	return transport
+}
+
 // HealthStatus represents the possible states of runner health monitoring
 type HealthStatus int
 
@@ -51,7 +61,8 @@ func sendRunnerHealthCheckRequest(runnerServerURI string) error {
 	url := fmt.Sprintf("%s/healthz", runnerServerURI)
 
 	client := &http.Client{
-		Timeout: healthCheckTimeout,
+		Transport: runnerHealthCheckTransport,
+		Timeout:   healthCheckTimeout,
 	}
 
 	resp, err := client.Get(url)
@@ -81,7 +92,13 @@ func monitorRunnerHealth(
 		defer wg.Done()
 		defer close(resultChan)
 
-		time.Sleep(initialDelay)
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopped monitoring runner health")
+			resultChan <- healthCheckResult{Status: StatusMonitoringCancelled}
+			return
+		case <-time.After(initialDelay):
+		}
 
 		failureCount := 0
 		ticker := time.NewTicker(healthCheckInterval)
@@ -113,10 +130,11 @@ func monitorRunnerHealth(
 	return resultChan
 }
 
-// ManageRunnerHealth monitors runner health and terminates it if unhealthy.
+// ManageRunnerHealth monitors runner health and terminates the runner via the
+// given function if unhealthy.
 func ManageRunnerHealth(
 	ctx context.Context,
-	cmd *exec.Cmd,
+	terminate func() error,
 	runnerServerURI string,
 	wg *sync.WaitGroup,
 	logger *logs.Logger,
@@ -128,11 +146,11 @@ func ManageRunnerHealth(
 		switch result.Status {
 		case StatusUnhealthy:
 			logger.Warn("Found runner unresponsive too many times, terminating runner...")
-			if err := cmd.Process.Kill(); err != nil {
-				panic(fmt.Errorf("failed to terminate unhealthy runner process: %v", err))
+			if err := terminate(); err != nil {
+				logger.Errorf("Failed to terminate unhealthy runner process: %v", err)
 			}
 		case StatusMonitoringCancelled:
-			// On cancellation via context, CommandContext will terminate the process, so no action.
+			// On cancellation via context, the runner has exited or is being terminated on shutdown, so no action.
 		}
 	}()
 }
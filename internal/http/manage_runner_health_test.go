@@ -2,12 +2,16 @@ package http
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os/exec"
 	"sync"
 	"syscall"
 	"task-runner-launcher/internal/logs"
+	"task-runner-launcher/internal/process"
+	"task-runner-launcher/internal/status"
 	"testing"
 	"time"
 
@@ -68,6 +72,10 @@ func TestSendRunnerHealthCheckRequest(t *testing.T) {
 	}
 }
 
+func TestRunnerHealthCheckBypassesProxy(t *testing.T) {
+	assert.Nil(t, runnerHealthCheckTransport.Proxy, "runner health checks must not go through a proxy")
+}
+
 func TestMonitorRunnerHealth(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -157,11 +165,12 @@ func TestManageRunnerHealth(t *testing.T) {
 			defer srv.Close()
 
 			cmd := exec.Command("sleep", "60")
-			require.NoError(t, cmd.Start(), "Failed to start long-running dummy process")
+			runner, err := process.Start(cmd, io.Discard, io.Discard)
+			require.NoError(t, err, "Failed to start long-running dummy process")
 
 			done := make(chan error) // to help monitor process state
 			go func() {
-				done <- cmd.Wait()
+				done <- runner.Wait()
 			}()
 
 			var wg sync.WaitGroup
@@ -169,7 +178,10 @@ func TestManageRunnerHealth(t *testing.T) {
 			defer cancel()
 
 			logger := logs.NewLogger(logs.InfoLevel, "")
-			ManageRunnerHealth(ctx, cmd, srv.URL, &wg, logger)
+			terminate := func() error {
+				return runner.Terminate(string(status.KillReasonUnhealthy), 10*time.Millisecond)
+			}
+			ManageRunnerHealth(ctx, terminate, srv.URL, &wg, logger)
 
 			// For a healthy runner, we wait long enough for 3 health checks to pass.
 			// For an unhealthy runner, we wait long enough for 2 health checks to
@@ -181,6 +193,7 @@ func TestManageRunnerHealth(t *testing.T) {
 			select {
 			case <-done:
 				assert.True(t, tt.expectKill, "Process was killed but should have been left running")
+				assert.Equal(t, string(status.KillReasonUnhealthy), runner.TerminateReason())
 
 			case <-time.After(100 * time.Millisecond):
 				if tt.expectKill {
@@ -217,3 +230,33 @@ func TestContextCancellation(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestManageRunnerHealthTerminationFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	logger := logs.NewLogger(logs.InfoLevel, "")
+
+	terminated := make(chan struct{})
+	terminate := func() error {
+		close(terminated)
+		return errors.New("operation not permitted")
+	}
+
+	// should log the failure rather than panic
+	ManageRunnerHealth(ctx, terminate, srv.URL, &wg, logger)
+
+	select {
+	case <-terminated:
+	case <-time.After(time.Second):
+		t.Fatal("Expected unhealthy runner to be terminated")
+	}
+
+	wg.Wait()
+}
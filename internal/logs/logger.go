@@ -2,6 +2,7 @@ package logs
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -75,6 +76,25 @@ func GetRunnerPrefix(runnerType string) string {
 	return fmt.Sprintf("[runner:%s] ", runnerType)
 }
 
+// GetRunnerInstancePrefix returns the formatted prefix for logs of one of
+// multiple instances of a runner type, numbered from 1.
+func GetRunnerInstancePrefix(runnerType string, instance int) string {
+	if abbr, ok := abbreviations[runnerType]; ok {
+		return fmt.Sprintf("[runner:%s#%d] ", abbr, instance)
+	}
+
+	return fmt.Sprintf("[runner:%s#%d] ", runnerType, instance)
+}
+
+// GetHookPrefix returns the formatted prefix for logs of a hook of a runner type.
+func GetHookPrefix(runnerType string, hook string) string {
+	if abbr, ok := abbreviations[runnerType]; ok {
+		return fmt.Sprintf("[hook:%s:%s] ", abbr, hook)
+	}
+
+	return fmt.Sprintf("[hook:%s:%s] ", runnerType, hook)
+}
+
 // ------------------------
 //         logger
 // ------------------------
@@ -101,6 +121,15 @@ func NewLogger(level Level, prefix string) *Logger {
 
 var logger = NewLogger(InfoLevel, "")
 
+// SetOutput sets the output of the global logger for all levels, e.g. to keep
+// logs out of machine-readable output on stdout.
+func SetOutput(w io.Writer) {
+	logger.debug.SetOutput(w)
+	logger.info.SetOutput(w)
+	logger.warn.SetOutput(w)
+	logger.err.SetOutput(w)
+}
+
 func (l *Logger) Debug(msg string) {
 	if l.level <= DebugLevel {
 		l.debug.Printf("%sDEBUG %s%s%s", ColorCyan, l.prefix, msg, ColorReset)
@@ -291,3 +291,61 @@ func TestGetRunnerPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRunnerInstancePrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		runnerType string
+		instance   int
+		expected   string
+	}{
+		{
+			name:       "javascript abbreviation",
+			runnerType: "javascript",
+			instance:   1,
+			expected:   "[runner:js#1] ",
+		},
+		{
+			name:       "unknown runner type uses raw name",
+			runnerType: "go",
+			instance:   2,
+			expected:   "[runner:go#2] ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetRunnerInstancePrefix(tt.runnerType, tt.instance)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestGetHookPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		runnerType string
+		hook       string
+		expected   string
+	}{
+		{
+			name:       "python abbreviation",
+			runnerType: "python",
+			hook:       "pre-launch",
+			expected:   "[hook:py:pre-launch] ",
+		},
+		{
+			name:       "unknown runner type uses raw name",
+			runnerType: "go",
+			hook:       "post-exit",
+			expected:   "[hook:go:post-exit] ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetHookPrefix(tt.runnerType, tt.hook)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -58,3 +58,13 @@ func GetRunnerWriters(minLevel Level, prefix string) (stdout io.Writer, stderr i
 
 	return stdout, stderr
 }
+
+// GetHookWriters returns configured `stdout` and `stderr` writers for hook
+// output with a custom prefix. Unlike runner output, hook stdout is logged at
+// info level.
+func GetHookWriters(minLevel Level, prefix string) (stdout io.Writer, stderr io.Writer) {
+	stdout = NewRunnerWriter(os.Stdout, prefix, ColorBlue, InfoLevel, minLevel)
+	stderr = NewRunnerWriter(os.Stderr, prefix, ColorRed, ErrorLevel, minLevel)
+
+	return stdout, stderr
+}
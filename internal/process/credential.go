@@ -0,0 +1,27 @@
+package process
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const (
+	capSetgid = 6
+	capSetuid = 7
+)
+
+// CheckCredential returns an error if the launcher lacks the capabilities to
+// start a process with the given credential, i.e. CAP_SETUID to switch user
+// and CAP_SETGID to switch group and set supplementary groups.
+func CheckCredential(cred *syscall.Credential) error {
+	if cred.Uid != uint32(syscall.Geteuid()) && !hasCapability(capSetuid) {
+		return fmt.Errorf("launcher lacks CAP_SETUID to switch to user %d", cred.Uid)
+	}
+
+	// supplementary groups are always set, so as to drop the launcher's own
+	if !hasCapability(capSetgid) {
+		return fmt.Errorf("launcher lacks CAP_SETGID to switch to group %d", cred.Gid)
+	}
+
+	return nil
+}
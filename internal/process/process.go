@@ -0,0 +1,208 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// trackedMu guards the set of live commands started by the launcher, so that
+	// cleaning up after one command never affects a command that is starting up
+	// or still running.
+	trackedMu sync.Mutex
+
+	// tracked holds the PIDs of live commands started by the launcher, with their
+	// start times as returned by `startTime`.
+	tracked = make(map[int]uint64)
+)
+
+// Group is a command started as the leader of its own session and process group,
+// so that its whole process tree can be signalled and cleaned up together.
+type Group struct {
+	cmd    *exec.Cmd
+	output sync.WaitGroup
+
+	// exited is closed once the group leader has exited.
+	exited chan struct{}
+
+	terminateMu     sync.Mutex
+	terminateReason string
+}
+
+// Command returns a command to start with `Start`, to be terminated via
+// `Group.Terminate`.
+func Command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	return cmd
+}
+
+// Start starts the command in a new session, relaying its output to the given
+// writers. The output of the command's process tree is relayed until every
+// process in the tree has exited.
+func Start(cmd *exec.Cmd, stdout, stderr io.Writer) (*Group, error) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+
+	g := &Group{cmd: cmd, exited: make(chan struct{})}
+
+	// Relay output via pipes owned by the launcher rather than by `exec.Cmd`, so
+	// that `Wait` does not block on descendants that keep the pipes open.
+	readers := make([]*os.File, 0, 2)
+	writers := make([]*os.File, 0, 2)
+	for range 2 {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeAll(readers)
+			closeAll(writers)
+			return nil, err
+		}
+		readers = append(readers, r)
+		writers = append(writers, w)
+	}
+	cmd.Stdout, cmd.Stderr = writers[0], writers[1]
+
+	trackedMu.Lock()
+	err := cmd.Start()
+	if err == nil {
+		tracked[cmd.Process.Pid] = startTime(cmd.Process.Pid)
+	}
+	trackedMu.Unlock()
+
+	closeAll(writers)
+
+	if err != nil {
+		closeAll(readers)
+		return nil, err
+	}
+
+	for i, w := range []io.Writer{stdout, stderr} {
+		g.output.Add(1)
+		go func(r *os.File) {
+			defer g.output.Done()
+			defer r.Close()
+			_, _ = io.Copy(w, r)
+		}(readers[i])
+	}
+
+	return g, nil
+}
+
+// Pid returns the PID of the group leader.
+func (g *Group) Pid() int {
+	return g.cmd.Process.Pid
+}
+
+// Signal sends a signal to every process in the group.
+func (g *Group) Signal(sig syscall.Signal) error {
+	return signalGroup(g.Pid(), sig)
+}
+
+// Terminate records why the group is being terminated and sends SIGTERM to
+// every process in the group, then waits for the group leader to exit. If the
+// leader has not exited within the grace period, every process in the group is
+// killed. Only the reason of the first call is kept. `Wait` must be called
+// concurrently for the leader's exit to be noticed.
+func (g *Group) Terminate(reason string, gracePeriod time.Duration) error {
+	if err := g.Drain(reason); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-g.exited:
+		return nil
+	case <-timer.C:
+	}
+
+	if err := g.Signal(syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to send SIGKILL after grace period of %v: %w", gracePeriod, err)
+	}
+
+	return nil
+}
+
+// Drain records why the group is being terminated and sends SIGTERM to every
+// process in the group, leaving the group leader to exit in its own time, e.g.
+// once it has finished its in-flight tasks. Only the reason of the first call
+// to `Drain` or `Terminate` is kept.
+func (g *Group) Drain(reason string) error {
+	g.terminateMu.Lock()
+	if g.terminateReason == "" {
+		g.terminateReason = reason
+	}
+	g.terminateMu.Unlock()
+
+	if err := g.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to send SIGTERM: %w", err)
+	}
+
+	return nil
+}
+
+// TerminateReason returns the reason the group was terminated for, if it was
+// terminated via `Terminate`.
+func (g *Group) TerminateReason() string {
+	g.terminateMu.Lock()
+	defer g.terminateMu.Unlock()
+
+	return g.terminateReason
+}
+
+// Wait waits for the group leader to exit, then kills every process left over
+// from its process tree, and returns the result of `exec.Cmd.Wait`.
+func (g *Group) Wait() error {
+	pid := g.Pid()
+
+	// Until the exited leader is reaped, its PID cannot be reused, so the group
+	// is best signalled before reaping the leader.
+	isAwaited := awaitExit(pid)
+	if isAwaited {
+		close(g.exited)
+		_ = signalGroup(pid, syscall.SIGKILL)
+	}
+
+	err := g.cmd.Wait()
+
+	if !isAwaited {
+		close(g.exited)
+		_ = signalGroup(pid, syscall.SIGKILL)
+	}
+
+	trackedMu.Lock()
+	delete(tracked, pid)
+	killDescendants(pid)
+	trackedMu.Unlock()
+
+	g.output.Wait()
+
+	return err
+}
+
+func signalGroup(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+
+	return nil
+}
+
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
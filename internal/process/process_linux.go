@@ -0,0 +1,199 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"task-runner-launcher/internal/logs"
+	"time"
+	"unsafe"
+)
+
+const (
+	// prSetChildSubreaper is the `prctl` option to mark a process as a subreaper.
+	prSetChildSubreaper = 36
+
+	// waitidPID and waitNoWait are the `waitid` options to wait for a specific PID
+	// and to leave the child waitable after it has exited, respectively.
+	waitidPID  = 1
+	waitNoWait = 0x1000000
+
+	// descendantsScanAttempts is the max number of scans of `/proc` for processes
+	// left over from a command's process tree, to account for orphans being
+	// reparented to the launcher with a slight delay.
+	descendantsScanAttempts = 5
+
+	descendantsScanInterval = 10 * time.Millisecond
+)
+
+// EnableSubreaper marks the launcher as a subreaper, so that orphaned descendants
+// of runners are reparented to the launcher rather than to init, which allows
+// the launcher to find and kill them after the runner exits.
+func EnableSubreaper() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// awaitExit blocks until the child with the given PID has exited, without
+// reaping it. Returns false if the child could not be awaited.
+func awaitExit(pid int) bool {
+	var info [128]byte // siginfo_t
+	for {
+		_, _, errno := syscall.Syscall6(
+			syscall.SYS_WAITID,
+			waitidPID,
+			uintptr(pid),
+			uintptr(unsafe.Pointer(&info[0])),
+			syscall.WEXITED|waitNoWait,
+			0,
+			0,
+		)
+		if errno != syscall.EINTR {
+			return errno == 0
+		}
+	}
+}
+
+// procStat holds the relevant fields of `/proc/[pid]/stat`.
+type procStat struct {
+	pid     int
+	ppid    int
+	pgid    int
+	session int
+
+	// startTime is the time the process started at, in clock ticks since boot.
+	startTime uint64
+}
+
+func readProcStat(pid int) (procStat, error) {
+	// #nosec G304 -- path is built from a numeric PID
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+
+	// comm (2nd field) is parenthesized and may contain spaces, so parse after it
+	content := string(data)
+	fields := strings.Fields(content[strings.LastIndexByte(content, ')')+1:])
+	if len(fields) < 20 {
+		return procStat{}, syscall.EINVAL
+	}
+
+	stat := procStat{pid: pid}
+	stat.ppid, _ = strconv.Atoi(fields[1])
+	stat.pgid, _ = strconv.Atoi(fields[2])
+	stat.session, _ = strconv.Atoi(fields[3])
+	stat.startTime, _ = strconv.ParseUint(fields[19], 10, 64)
+
+	return stat, nil
+}
+
+// startTime returns the time the process with the given PID started at, in
+// clock ticks since boot, or 0 if unknown.
+func startTime(pid int) uint64 {
+	stat, err := readProcStat(pid)
+	if err != nil {
+		return 0
+	}
+
+	return stat.startTime
+}
+
+// mayDescendFromLive returns whether the process may descend from a live
+// command, i.e. started no earlier than any live command. Must be called with
+// `trackedMu` held.
+func mayDescendFromLive(stat procStat) bool {
+	for _, start := range tracked {
+		if start <= stat.startTime {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findDescendants returns the processes in `/proc` left over from the process
+// tree of the exited session leader with the given PID, i.e. processes still in
+// its session or process group, and orphans reparented to the launcher that left
+// its session and cannot descend from a live command. As such an orphan could
+// be left over from any command that was live when it started, it is only
+// returned once all those commands have exited. Must be called with `trackedMu`
+// held.
+func findDescendants(leaderPid int) []procStat {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+
+	var found []procStat
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+
+		stat, err := readProcStat(pid)
+		if err != nil {
+			continue // process already gone
+		}
+
+		_, isTracked := tracked[pid]
+		isOrphan := stat.ppid == self && !isTracked && !mayDescendFromLive(stat)
+
+		if stat.session == leaderPid || stat.pgid == leaderPid || isOrphan {
+			found = append(found, stat)
+		}
+	}
+
+	return found
+}
+
+// killDescendants kills and reaps every process left over from the process tree
+// of the exited session leader with the given PID. Must be called with
+// `trackedMu` held.
+func killDescendants(leaderPid int) {
+	self := os.Getpid()
+
+	for range descendantsScanAttempts {
+		descendants := findDescendants(leaderPid)
+		if len(descendants) == 0 {
+			return
+		}
+
+		for _, d := range descendants {
+			logs.Debugf("Killing process %d left over from process %d", d.pid, leaderPid)
+			_ = syscall.Kill(d.pid, syscall.SIGKILL)
+			if d.ppid == self {
+				var status syscall.WaitStatus
+				_, _ = syscall.Wait4(d.pid, &status, 0, nil)
+			}
+		}
+
+		time.Sleep(descendantsScanInterval)
+	}
+}
+
+// hasCapability returns whether the given capability is in the launcher's
+// effective capability set.
+func hasCapability(capability uint) bool {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(status), "\n") {
+		if hex, found := strings.CutPrefix(line, "CapEff:"); found {
+			caps, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+			return err == nil && caps&(1<<capability) != 0
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package process
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitKillsLeftoverProcesses(t *testing.T) {
+	require.NoError(t, EnableSubreaper(), "Failed to enable subreaper")
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name:   "background child in same session",
+			script: "sleep 60 >/dev/null 2>&1 & echo $!",
+		},
+		{
+			name:   "background child in new session",
+			script: "setsid sleep 60 >/dev/null 2>&1 & echo $!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr syncBuffer
+
+			g, err := Start(exec.Command("sh", "-c", tt.script), &stdout, &stderr)
+			require.NoError(t, err, "Failed to start command")
+
+			require.NoError(t, g.Wait(), "Unexpected error from command")
+
+			childPid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+			require.NoError(t, err, "Failed to read PID of background child")
+
+			err = syscall.Kill(childPid, 0)
+			assert.ErrorIs(t, err, syscall.ESRCH, "Expected background child to be killed and reaped")
+		})
+	}
+}
+
+func TestWaitSparesOrphansOfLiveCommands(t *testing.T) {
+	require.NoError(t, EnableSubreaper(), "Failed to enable subreaper")
+
+	// each command leaves behind an orphan in a new session, reparented to the
+	// launcher while the command is still running
+	const script = "(setsid sleep 60 >/dev/null 2>&1 & echo $!); sleep %s"
+
+	startWithOrphan := func(lifetime string) (*Group, int) {
+		var stdout, stderr syncBuffer
+		g, err := Start(exec.Command("sh", "-c", fmt.Sprintf(script, lifetime)), &stdout, &stderr)
+		require.NoError(t, err, "Failed to start command")
+
+		var orphanPid int
+		require.Eventually(t, func() bool {
+			orphanPid, err = strconv.Atoi(strings.TrimSpace(stdout.String()))
+			return err == nil
+		}, time.Second, 10*time.Millisecond, "Failed to read PID of orphan")
+
+		return g, orphanPid
+	}
+
+	exiting, exitingOrphan := startWithOrphan("0.3")
+	time.Sleep(50 * time.Millisecond) // let the live command start in a later clock tick
+	live, liveOrphan := startWithOrphan("60")
+
+	require.NoError(t, exiting.Wait(), "Unexpected error from command")
+
+	assert.ErrorIs(t, syscall.Kill(exitingOrphan, 0), syscall.ESRCH, "Expected orphan of exited command to be killed and reaped")
+	assert.NoError(t, syscall.Kill(liveOrphan, 0), "Expected orphan of live command to be spared")
+
+	require.NoError(t, live.Terminate("", time.Second))
+	_ = live.Wait()
+
+	assert.ErrorIs(t, syscall.Kill(liveOrphan, 0), syscall.ESRCH, "Expected orphan to be killed once its command exited")
+}
+
+func TestReadProcStat(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	require.NoError(t, cmd.Start(), "Failed to start command")
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	stat, err := readProcStat(cmd.Process.Pid)
+	require.NoError(t, err, "Failed to read stat")
+
+	assert.Equal(t, cmd.Process.Pid, stat.pid)
+	assert.Equal(t, syscall.Getpid(), stat.ppid)
+	assert.Equal(t, cmd.Process.Pid, stat.pgid)
+	assert.Equal(t, cmd.Process.Pid, stat.session)
+	assert.NotZero(t, stat.startTime)
+}
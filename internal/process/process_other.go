@@ -0,0 +1,30 @@
+//go:build !linux
+
+package process
+
+import "syscall"
+
+// EnableSubreaper is a no-op outside Linux.
+func EnableSubreaper() error {
+	return nil
+}
+
+// awaitExit is unsupported outside Linux, where the group is signalled only
+// after the leader is reaped.
+func awaitExit(_ int) bool {
+	return false
+}
+
+// startTime is unknown outside Linux.
+func startTime(_ int) uint64 {
+	return 0
+}
+
+// killDescendants is a no-op outside Linux, where only the leader's process
+// group is killed.
+func killDescendants(_ int) {}
+
+// hasCapability returns whether the launcher runs as root, outside Linux.
+func hasCapability(_ uint) bool {
+	return syscall.Geteuid() == 0
+}
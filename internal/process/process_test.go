@@ -0,0 +1,110 @@
+package process
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a buffer safe for concurrent writes and reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStartRelaysOutput(t *testing.T) {
+	var stdout, stderr syncBuffer
+
+	cmd := exec.Command("sh", "-c", "echo to-stdout; echo to-stderr >&2")
+	g, err := Start(cmd, &stdout, &stderr)
+	require.NoError(t, err, "Failed to start command")
+
+	require.NoError(t, g.Wait(), "Unexpected error from command")
+
+	assert.Equal(t, "to-stdout\n", stdout.String())
+	assert.Equal(t, "to-stderr\n", stderr.String())
+}
+
+func TestStartFailure(t *testing.T) {
+	var stdout, stderr syncBuffer
+
+	_, err := Start(exec.Command("/nonexistent/command"), &stdout, &stderr)
+
+	assert.Error(t, err, "Expected error for nonexistent command")
+}
+
+func TestTerminate(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		expectKilled bool
+	}{
+		{
+			// background child keeps running unless it also receives SIGTERM
+			name:   "exits on SIGTERM before grace period",
+			script: "trap 'exit 0' TERM; sleep 60 & wait",
+		},
+		{
+			name:         "killed after grace period",
+			script:       "trap '' TERM; sleep 60 & wait; wait",
+			expectKilled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr syncBuffer
+
+			cmd := Command("sh", "-c", tt.script)
+			g, err := Start(cmd, &stdout, &stderr)
+			require.NoError(t, err, "Failed to start command")
+
+			done := make(chan struct{})
+			go func() {
+				_ = g.Wait()
+				close(done)
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			require.NoError(t, g.Terminate("shutdown", 200*time.Millisecond))
+
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Expected command to exit on termination")
+			}
+
+			assert.Equal(t, "shutdown", g.TerminateReason())
+			status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+			assert.Equal(t, tt.expectKilled, status.Signaled() && status.Signal() == syscall.SIGKILL)
+		})
+	}
+}
+
+func TestTerminateExitedGroup(t *testing.T) {
+	cmd := Command("true")
+	g, err := Start(cmd, io.Discard, io.Discard)
+	require.NoError(t, err, "Failed to start command")
+	require.NoError(t, g.Wait())
+
+	assert.NoError(t, g.Terminate("unhealthy", time.Second))
+}
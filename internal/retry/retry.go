@@ -1,6 +1,8 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"task-runner-launcher/internal/logs"
 	"time"
@@ -25,7 +27,7 @@ type retryConfig struct {
 	WaitTimeBetweenRetries time.Duration
 }
 
-func retry[T any](operationName string, operationFn func() (T, error), cfg retryConfig) (T, error) {
+func retry[T any](ctx context.Context, operationName string, operationFn func() (T, error), cfg retryConfig) (T, error) {
 	var lastErr error
 	var zero T
 	startTime := time.Now()
@@ -59,22 +61,27 @@ func retry[T any](operationName string, operationFn func() (T, error), cfg retry
 		logs.Debugf("Attempt %d for operation `%s` failed, error: %v", attempt, operationName, err)
 		attempt++
 
-		time.Sleep(cfg.WaitTimeBetweenRetries)
+		select {
+		case <-ctx.Done():
+			return zero, fmt.Errorf("stopped retrying operation `%s` on cancellation, last error: %w", operationName, errors.Join(ctx.Err(), lastErr))
+		case <-time.After(cfg.WaitTimeBetweenRetries):
+		}
 	}
 }
 
-// UnlimitedRetry retries an operation forever.
-func UnlimitedRetry[T any](operationName string, operationFn func() (T, error)) (T, error) {
-	return retry(operationName, operationFn, retryConfig{
+// UnlimitedRetry retries an operation forever, or until the context is cancelled.
+func UnlimitedRetry[T any](ctx context.Context, operationName string, operationFn func() (T, error)) (T, error) {
+	return retry(ctx, operationName, operationFn, retryConfig{
 		MaxRetryTime:           0,
 		MaxAttempts:            0,
 		WaitTimeBetweenRetries: DefaultWaitTimeBetweenRetries,
 	})
 }
 
-// LimitedRetry retries an operation until max retry time or until max attempts.
-func LimitedRetry[T any](operationName string, operationFn func() (T, error)) (T, error) {
-	return retry(operationName, operationFn, retryConfig{
+// LimitedRetry retries an operation until max retry time or until max attempts,
+// or until the context is cancelled.
+func LimitedRetry[T any](ctx context.Context, operationName string, operationFn func() (T, error)) (T, error) {
+	return retry(ctx, operationName, operationFn, retryConfig{
 		MaxRetryTime:           DefaultMaxRetryTime,
 		MaxAttempts:            DefaultMaxRetries,
 		WaitTimeBetweenRetries: DefaultWaitTimeBetweenRetries,
@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -71,7 +72,7 @@ func TestUnlimitedRetry(t *testing.T) {
 				return tt.operationFn()
 			}
 
-			result, err := UnlimitedRetry("test-operation", trackedFn)
+			result, err := UnlimitedRetry(context.Background(), "test-operation", trackedFn)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -150,7 +151,7 @@ func TestLimitedRetry(t *testing.T) {
 				return tt.operationFn()
 			}
 
-			result, err := LimitedRetry("test-operation", trackedFn)
+			result, err := LimitedRetry(context.Background(), "test-operation", trackedFn)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -199,7 +200,7 @@ func TestRetryConfiguration(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := retry("test", tt.fn, tt.cfg)
+			_, err := retry(context.Background(), "test", tt.fn, tt.cfg)
 			assert.Error(t, err)
 			assert.Equal(t, tt.want.Error(), err.Error())
 		})
@@ -208,7 +209,7 @@ func TestRetryConfiguration(t *testing.T) {
 
 func TestRetryWithDifferentTypes(t *testing.T) {
 	t.Run("works with string", func(t *testing.T) {
-		result, err := UnlimitedRetry("string-operation", func() (string, error) {
+		result, err := UnlimitedRetry(context.Background(), "string-operation", func() (string, error) {
 			return "test", nil
 		})
 
@@ -217,7 +218,7 @@ func TestRetryWithDifferentTypes(t *testing.T) {
 	})
 
 	t.Run("works with int", func(t *testing.T) {
-		result, err := UnlimitedRetry("int-operation", func() (int, error) {
+		result, err := UnlimitedRetry(context.Background(), "int-operation", func() (int, error) {
 			return 123, nil
 		})
 
@@ -230,7 +231,7 @@ func TestRetryWithDifferentTypes(t *testing.T) {
 	}
 
 	t.Run("works with struct", func(t *testing.T) {
-		result, err := UnlimitedRetry("struct-operation", func() (testStruct, error) {
+		result, err := UnlimitedRetry(context.Background(), "struct-operation", func() (testStruct, error) {
 			return testStruct{value: "test"}, nil
 		})
 
@@ -238,3 +239,23 @@ func TestRetryWithDifferentTypes(t *testing.T) {
 		assert.Equal(t, "test", result.value)
 	})
 }
+
+func TestRetryCancellation(t *testing.T) {
+	restoreFn := setRetryTimings(t)
+	defer restoreFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	callCount := 0
+	_, err := UnlimitedRetry(ctx, "test-operation", func() (string, error) {
+		callCount++
+		if callCount == 2 {
+			cancel()
+		}
+		return "", errors.New("persistent error")
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "persistent error")
+	assert.Equal(t, 2, callCount)
+}
@@ -0,0 +1,11 @@
+package seccomp
+
+import "golang.org/x/sys/unix"
+
+const (
+	auditArch = unix.AUDIT_ARCH_X86_64
+
+	// x32SyscallBit marks syscalls of the x32 ABI, which the filter does not
+	// cover and so treats with the profile's default action.
+	x32SyscallBit = 0x40000000
+)
@@ -0,0 +1,10 @@
+package seccomp
+
+import "golang.org/x/sys/unix"
+
+const (
+	auditArch = unix.AUDIT_ARCH_AARCH64
+
+	// x32SyscallBit is unused outside x86-64.
+	x32SyscallBit = 0
+)
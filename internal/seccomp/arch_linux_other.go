@@ -0,0 +1,12 @@
+//go:build linux && !amd64 && !arm64
+
+package seccomp
+
+const (
+	// auditArch is unset on architectures the launcher has no syscall table for.
+	auditArch = 0
+
+	x32SyscallBit = 0
+)
+
+var syscalls map[string]uint32
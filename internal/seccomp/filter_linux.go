@@ -0,0 +1,128 @@
+package seccomp
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// offsets of fields in `struct seccomp_data`
+const (
+	offsetNr   = 0
+	offsetArch = 4
+)
+
+// compile compiles the profile into a BPF program that checks the architecture
+// and then returns the action of the first rule naming the syscall, or else the
+// default action. In log-only mode, every action other than allowing the
+// syscall is replaced by logging it.
+func (p *Profile) compile(logOnly bool) ([]unix.SockFilter, error) {
+	if auditArch == 0 {
+		return nil, fmt.Errorf("seccomp profiles are not supported on %s", runtime.GOARCH)
+	}
+
+	ret := func(action Action, errnoRet *uint) unix.SockFilter {
+		return stmt(unix.BPF_RET|unix.BPF_K, actionValue(action, errnoRet, logOnly))
+	}
+
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, auditArch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetNr),
+	}
+
+	if x32SyscallBit != 0 {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 0, 1),
+			ret(p.DefaultAction, p.DefaultErrnoRet),
+		)
+	}
+
+	for _, rule := range p.Syscalls {
+		for _, name := range rule.Names {
+			nr, ok := syscalls[name]
+			if !ok {
+				continue // not on this architecture
+			}
+			filter = append(filter,
+				jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+				ret(rule.Action, rule.ErrnoRet),
+			)
+		}
+	}
+
+	filter = append(filter, ret(p.DefaultAction, p.DefaultErrnoRet))
+
+	if len(filter) > unix.BPF_MAXINSNS {
+		return nil, errors.New("seccomp profile has too many syscalls")
+	}
+
+	return filter, nil
+}
+
+// Apply applies the profile to all threads of the current process, and to any
+// process it executes or starts thereafter. Also sets `no_new_privs`, as
+// required to apply a profile without CAP_SYS_ADMIN.
+func (p *Profile) Apply(logOnly bool) error {
+	filter, err := p.compile(logOnly)
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	// #nosec G103 -- the kernel reads the filter program via the pointer
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	if errno != 0 {
+		return fmt.Errorf("failed to apply seccomp profile: %w", errno)
+	}
+
+	return nil
+}
+
+func actionValue(action Action, errnoRet *uint, logOnly bool) uint32 {
+	if logOnly && action != ActAllow {
+		return unix.SECCOMP_RET_LOG
+	}
+
+	switch action {
+	case ActErrno:
+		return unix.SECCOMP_RET_ERRNO | (errno(errnoRet) & unix.SECCOMP_RET_DATA)
+	case ActKill:
+		return unix.SECCOMP_RET_KILL_THREAD
+	case ActKillProcess:
+		return unix.SECCOMP_RET_KILL_PROCESS
+	case ActLog:
+		return unix.SECCOMP_RET_LOG
+	default:
+		return unix.SECCOMP_RET_ALLOW
+	}
+}
+
+func stmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// errno returns the errno to return for a syscall with the action
+// `SCMP_ACT_ERRNO`, EPERM unless otherwise set.
+func errno(ret *uint) uint32 {
+	if ret == nil {
+		return uint32(syscall.EPERM)
+	}
+
+	return uint32(*ret)
+}
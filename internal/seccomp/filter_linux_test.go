@@ -0,0 +1,43 @@
+package seccomp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestCompile(t *testing.T) {
+	eacces := uint(unix.EACCES)
+	profile := &Profile{
+		DefaultAction: ActAllow,
+		Syscalls: []Rule{
+			{Names: []string{"ptrace", "no_such_syscall"}, Action: ActKillProcess},
+			{Names: []string{"mkdirat"}, Action: ActErrno, ErrnoRet: &eacces},
+		},
+	}
+
+	t.Run("enforcing", func(t *testing.T) {
+		filter, err := profile.compile(false)
+		require.NoError(t, err)
+
+		last := filter[len(filter)-1]
+		assert.Equal(t, uint32(unix.SECCOMP_RET_ALLOW), last.K, "default action should be last")
+
+		assert.Contains(t, filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_PTRACE, 0, 1))
+		assert.Contains(t, filter, stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS))
+		assert.Contains(t, filter, stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.EACCES)))
+	})
+
+	t.Run("log-only", func(t *testing.T) {
+		filter, err := profile.compile(true)
+		require.NoError(t, err)
+
+		for _, instruction := range filter[3:] {
+			if instruction.Code == unix.BPF_RET|unix.BPF_K {
+				assert.Contains(t, []uint32{unix.SECCOMP_RET_ALLOW, unix.SECCOMP_RET_LOG}, instruction.K)
+			}
+		}
+	})
+}
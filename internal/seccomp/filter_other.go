@@ -0,0 +1,10 @@
+//go:build !linux
+
+package seccomp
+
+import "errors"
+
+// Apply is unsupported outside Linux.
+func (p *Profile) Apply(_ bool) error {
+	return errors.New("seccomp profiles are supported only on Linux")
+}
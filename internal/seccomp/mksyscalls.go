@@ -0,0 +1,57 @@
+//go:build ignore
+
+// mksyscalls generates the table of syscall numbers by name for the given
+// architecture from the syscall numbers in golang.org/x/sys/unix.
+//
+// Usage: go run mksyscalls.go <arch>
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var sysnum = regexp.MustCompile(`(?m)^\s*SYS_(\w+)\s*=\s*\d+`)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: go run mksyscalls.go <arch>")
+	}
+	arch := os.Args[1]
+
+	dir, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "golang.org/x/sys").Output()
+	if err != nil {
+		log.Fatalf("failed to locate golang.org/x/sys: %v", err)
+	}
+
+	src, err := os.ReadFile(filepath.Join(strings.TrimSpace(string(dir)), "unix", fmt.Sprintf("zsysnum_linux_%s.go", arch)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by `go run mksyscalls.go %s`. DO NOT EDIT.\n\n", arch)
+	fmt.Fprintf(&buf, "package seccomp\n\nimport \"golang.org/x/sys/unix\"\n\n")
+	fmt.Fprintf(&buf, "// syscalls maps syscall names to syscall numbers.\n")
+	fmt.Fprintf(&buf, "var syscalls = map[string]uint32{\n")
+	for _, match := range sysnum.FindAllStringSubmatch(string(src), -1) {
+		fmt.Fprintf(&buf, "\t%q: unix.SYS_%s,\n", strings.ToLower(match[1]), match[1])
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(fmt.Sprintf("syscalls_linux_%s.go", arch), out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
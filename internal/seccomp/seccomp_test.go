@@ -0,0 +1,90 @@
+package seccomp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       string
+		expectedError string
+	}{
+		{
+			name:    "valid profile",
+			profile: `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"}]}`,
+		},
+		{
+			name:    "empty args",
+			profile: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["ptrace"], "action": "SCMP_ACT_KILL", "args": []}]}`,
+		},
+		{
+			name:          "invalid json",
+			profile:       `{"defaultAction": `,
+			expectedError: "failed to parse seccomp profile",
+		},
+		{
+			name:          "missing default action",
+			profile:       `{"syscalls": []}`,
+			expectedError: "defaultAction: action is required",
+		},
+		{
+			name:          "unsupported action",
+			profile:       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["ptrace"], "action": "SCMP_ACT_TRACE"}]}`,
+			expectedError: "syscalls[0]: unsupported action SCMP_ACT_TRACE",
+		},
+		{
+			name:          "empty names",
+			profile:       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": [], "action": "SCMP_ACT_ERRNO"}]}`,
+			expectedError: "syscalls[0]: names must not be empty",
+		},
+		{
+			name: "conditions on args",
+			profile: `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["personality"], "action": "SCMP_ACT_ERRNO", ` +
+				`"args": [{"index": 0, "value": 8, "op": "SCMP_CMP_EQ"}]}]}`,
+			expectedError: "syscalls[0]: conditions on args are not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProfile([]byte(tt.profile))
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"defaultAction": "SCMP_ACT_LOG", "syscalls": []}`), 0o600))
+
+	profile, err := LoadProfile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, ActLog, profile.DefaultAction)
+
+	_, err = LoadProfile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read seccomp profile")
+}
+
+func TestDefaultProfile(t *testing.T) {
+	for _, runnerType := range []string{"javascript", "python"} {
+		profile, ok := DefaultProfile(runnerType)
+		require.True(t, ok, runnerType)
+		assert.Equal(t, ActAllow, profile.DefaultAction)
+		assert.Contains(t, profile.Syscalls[0].Names, "ptrace")
+		assert.NotContains(t, profile.Syscalls[0].Names, "execve", "runner must be able to execute")
+	}
+
+	_, ok := DefaultProfile("rust")
+	assert.False(t, ok)
+}
@@ -0,0 +1,75 @@
+package shim
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"task-runner-launcher/internal/config"
+
+	"golang.org/x/sys/unix"
+)
+
+// rlimitResources maps the rlimit names supported in the config to resources.
+var rlimitResources = map[string]int{
+	"RLIMIT_AS":      unix.RLIMIT_AS,
+	"RLIMIT_CORE":    unix.RLIMIT_CORE,
+	"RLIMIT_CPU":     unix.RLIMIT_CPU,
+	"RLIMIT_DATA":    unix.RLIMIT_DATA,
+	"RLIMIT_FSIZE":   unix.RLIMIT_FSIZE,
+	"RLIMIT_MEMLOCK": unix.RLIMIT_MEMLOCK,
+	"RLIMIT_NOFILE":  unix.RLIMIT_NOFILE,
+	"RLIMIT_NPROC":   unix.RLIMIT_NPROC,
+	"RLIMIT_STACK":   unix.RLIMIT_STACK,
+}
+
+// rlimitSignals maps the signals that the kernel sends to a process on
+// exceeding a soft rlimit to the name of that rlimit.
+var rlimitSignals = map[syscall.Signal]string{
+	syscall.SIGXCPU: "RLIMIT_CPU",
+	syscall.SIGXFSZ: "RLIMIT_FSIZE",
+}
+
+func setRlimits(rlimits map[string]config.Rlimit) error {
+	for name, rlimit := range rlimits {
+		resource, ok := rlimitResources[name]
+		if !ok {
+			return fmt.Errorf("unsupported rlimit %s", name)
+		}
+
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: rlimit.Soft, Max: rlimit.Hard}); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExceededRlimit returns the name of the rlimit that a process exceeded, if the
+// process was terminated by the signal the kernel sends on exceeding that limit,
+// or, if sandboxed, exited with 128 plus the number of that signal, as the shim
+// does as init of a sandbox on the runner being terminated by a signal. Outside
+// a sandbox, such an exit code is the runner's own.
+func ExceededRlimit(state *os.ProcessState, sandboxed bool) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return "", false
+	}
+
+	var sig syscall.Signal
+	switch {
+	case status.Signaled():
+		sig = status.Signal()
+	case sandboxed && status.Exited() && status.ExitStatus() > 128:
+		sig = syscall.Signal(status.ExitStatus() - 128)
+	default:
+		return "", false
+	}
+
+	name, ok := rlimitSignals[sig]
+
+	return name, ok
+}
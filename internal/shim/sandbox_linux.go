@@ -0,0 +1,302 @@
+package shim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"task-runner-launcher/internal/config"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// oldRoot is where the host's root filesystem is reachable from while the
+	// sandbox's root filesystem is being assembled at `newRoot`.
+	oldRoot = "/oldroot"
+	newRoot = "/newroot"
+
+	// baseDir is where a tmpfs is mounted to hold `oldRoot` and `newRoot`.
+	baseDir = "/tmp"
+)
+
+// devices are the device nodes mounted from the host into the sandbox's `/dev`.
+var devices = []string{"full", "null", "random", "tty", "urandom", "zero"}
+
+// devLinks are the symlinks created in the sandbox's `/dev`.
+var devLinks = map[string]string{
+	"fd":     "/proc/self/fd",
+	"stdin":  "/proc/self/fd/0",
+	"stdout": "/proc/self/fd/1",
+	"stderr": "/proc/self/fd/2",
+}
+
+// prepareSandbox sets up the command to start in new user, PID, mount, IPC and
+// UTS namespaces. Inside the user namespace, the runner keeps its UID and GIDs,
+// and the shim has CAP_SYS_ADMIN to assemble the sandbox, which is dropped when
+// the shim executes the runner.
+func prepareSandbox(cmd *exec.Cmd) error {
+	attr := cmd.SysProcAttr
+	if attr == nil {
+		attr = &syscall.SysProcAttr{}
+		cmd.SysProcAttr = attr
+	}
+
+	uid, gid := os.Geteuid(), os.Getegid()
+	var groups []uint32
+	if attr.Credential != nil {
+		uid, gid = int(attr.Credential.Uid), int(attr.Credential.Gid)
+		groups = attr.Credential.Groups
+	}
+
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	for _, group := range groups {
+		if int(group) != gid {
+			attr.GidMappings = append(attr.GidMappings, syscall.SysProcIDMap{ContainerID: int(group), HostID: int(group), Size: 1})
+		}
+	}
+	// only a privileged launcher may let the runner's credential set groups
+	attr.GidMappingsEnableSetgroups = os.Geteuid() == 0
+	attr.AmbientCaps = append(attr.AmbientCaps, unix.CAP_SYS_ADMIN)
+
+	return nil
+}
+
+// enterSandbox assembles the sandbox's root filesystem from the configured
+// mounts, a minimal `/dev`, a `/proc` for the new PID namespace and an optional
+// private `/tmp`, and switches into it. Finally, it drops the capabilities that
+// the shim was given to do so.
+func enterSandbox(sandbox *config.SandboxConfig) error {
+	// keep mounts from propagating back to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	// Assemble the new root in a tmpfs at `/tmp` while the host's root is moved
+	// to `oldRoot`, so that sources under the host's `/tmp` remain reachable.
+	if err := unix.Mount("tmpfs", baseDir, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount tmpfs: %w", err)
+	}
+	for _, dir := range []string{oldRoot, newRoot} {
+		if err := os.Mkdir(filepath.Join(baseDir, dir), 0o755); err != nil {
+			return err
+		}
+	}
+	if err := unix.PivotRoot(baseDir, filepath.Join(baseDir, oldRoot)); err != nil {
+		return fmt.Errorf("failed to switch to tmpfs: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	if err := unix.Mount("tmpfs", newRoot, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount root tmpfs: %w", err)
+	}
+
+	// mount before the configured mounts, so as not to hide any below `/tmp`
+	if sandbox.PrivateTmp {
+		if err := mountDir("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("failed to mount /tmp: %w", err)
+		}
+	}
+
+	for _, mount := range sandbox.Mounts() {
+		if err := bindMount(filepath.Join(oldRoot, mount.Source), filepath.Join(newRoot, mount.Target), mount); err != nil {
+			return err
+		}
+	}
+
+	if err := setUpDev(); err != nil {
+		return fmt.Errorf("failed to set up /dev: %w", err)
+	}
+
+	if err := mountDir("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %w", err)
+	}
+
+	if sandbox.Hostname != "" {
+		if err := unix.Sethostname([]byte(sandbox.Hostname)); err != nil {
+			return fmt.Errorf("failed to set hostname: %w", err)
+		}
+	}
+
+	if err := setReadOnly(newRoot, 0); err != nil {
+		return fmt.Errorf("failed to make root read-only: %w", err)
+	}
+
+	if err := unix.Unmount(oldRoot, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to unmount host root: %w", err)
+	}
+
+	if err := os.Chdir(newRoot); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to switch to sandbox root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to unmount tmpfs: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to drop capabilities: %w", err)
+	}
+
+	return nil
+}
+
+// bindMount mounts the source at the target, recursively, creating the target
+// as a file or dir to match the source.
+func bindMount(source, target string, mount config.Mount) error {
+	info, err := os.Stat(source)
+	if errors.Is(err, os.ErrNotExist) && mount.Optional {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mount %s: %w", mount.Source, err)
+	}
+
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else {
+		err = createFile(target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create mount point %s: %w", mount.Target, err)
+	}
+
+	if err := unix.Mount(source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to mount %s: %w", mount.Source, err)
+	}
+
+	if mount.ReadOnly {
+		if err := setReadOnly(target, unix.AT_RECURSIVE); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", mount.Target, err)
+		}
+	}
+
+	return nil
+}
+
+// setReadOnly makes the mount at the path read-only, including all mounts
+// below it if given AT_RECURSIVE.
+func setReadOnly(path string, flags uint) error {
+	return unix.MountSetattr(-1, path, flags, &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY})
+}
+
+// mountDir creates a dir in the new root and mounts a filesystem at it.
+func mountDir(source, dir, fstype string, flags uintptr, data string) error {
+	target := filepath.Join(newRoot, dir)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return err
+	}
+
+	return unix.Mount(source, target, fstype, flags, data)
+}
+
+func setUpDev() error {
+	if err := mountDir("tmpfs", "/dev", "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=0755"); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		target := filepath.Join(newRoot, "dev", device)
+		if err := createFile(target); err != nil {
+			return err
+		}
+		if err := unix.Mount(filepath.Join(oldRoot, "dev", device), target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("failed to mount /dev/%s: %w", device, err)
+		}
+	}
+
+	for name, dest := range devLinks {
+		if err := os.Symlink(dest, filepath.Join(newRoot, "dev", name)); err != nil {
+			return err
+		}
+	}
+
+	return mountDir("tmpfs", "/dev/shm", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+}
+
+func createFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// #nosec G304 -- path is within the sandbox's root filesystem
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// forwardedSignals are the signals that the shim, as init of the sandbox's PID
+// namespace, forwards to the runner's process group.
+var forwardedSignals = []os.Signal{
+	unix.SIGTERM, unix.SIGINT, unix.SIGHUP, unix.SIGQUIT, unix.SIGUSR1, unix.SIGUSR2,
+}
+
+// runInit runs the runner as the child of the shim, which acts as a minimal init
+// of the sandbox's PID namespace: it forwards signals to the runner's process
+// group, reaps every process orphaned in the namespace, and once the runner has
+// exited, exits with the runner's exit code, or 128 plus the number of the
+// signal that terminated the runner. As PID 1, the shim itself ignores any
+// signal it has no handler for, other than SIGKILL from the launcher.
+func runInit(spec Spec) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to encode shim spec: %w", err)
+	}
+
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, append(forwardedSignals, unix.SIGCHLD)...)
+
+	// The launcher's binary is not within the sandbox's root filesystem, but
+	// remains reachable via the shim's own process.
+	cmd := exec.Command("/proc/self/exe")
+	cmd.Args = os.Args
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", envVarSpec, data))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start runner in sandbox: %w", err)
+	}
+	pid := cmd.Process.Pid
+
+	for sig := range signals {
+		if sig != unix.SIGCHLD {
+			_ = unix.Kill(-pid, sig.(unix.Signal))
+			continue
+		}
+
+		for {
+			var status unix.WaitStatus
+			reaped, err := unix.Wait4(-1, &status, unix.WNOHANG, nil)
+			if err != nil || reaped <= 0 {
+				break
+			}
+			if reaped != pid {
+				continue
+			}
+
+			if status.Signaled() {
+				os.Exit(128 + int(status.Signal()))
+			}
+			os.Exit(status.ExitStatus())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package shim
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"task-runner-launcher/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runSandboxed runs the shell script in a sandbox, skipping the test if the
+// environment does not allow creating namespaces.
+func runSandboxed(t *testing.T, sandbox *config.SandboxConfig, dir, script string) string {
+	t.Helper()
+
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.Env = []string{"PATH=/usr/bin:/bin"}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	require.NoError(t, Wrap(cmd, Spec{Dir: dir, Sandbox: sandbox}))
+
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(err.Error(), "operation not permitted") || strings.Contains(stderr.String(), "permission denied") {
+			t.Skipf("Namespaces are unavailable: %v: %s", err, stderr.String())
+		}
+		require.NoError(t, err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func TestSandbox(t *testing.T) {
+	hostDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(hostDir, "input.txt"), []byte("hello"), 0o644))
+	outputDir := t.TempDir()
+
+	sandbox := &config.SandboxConfig{
+		ReadOnlyMounts:  []string{"/bin", "/lib", "/lib64", "/usr", hostDir + ":/data"},
+		ReadWriteMounts: []string{outputDir + ":/output"},
+		PrivateTmp:      true,
+		Hostname:        "sandboxed-runner",
+	}
+
+	output := runSandboxed(t, sandbox, "/data", strings.Join([]string{
+		"echo ppid=$PPID",
+		"echo cwd=$(pwd)",
+		"echo hostname=$(cat /proc/sys/kernel/hostname)",
+		"echo input=$(cat input.txt)",
+		"echo root=$(ls / | tr '\\n' ' ')",
+		"touch /data/x 2>/dev/null && echo data=rw || echo data=ro",
+		"echo written > /output/out.txt",
+		"touch /tmp/x && echo tmp=rw",
+	}, "; "))
+
+	assert.Contains(t, output, "ppid=1\n", "runner should be a child of the shim as init of new PID namespace")
+	assert.Contains(t, output, "cwd=/data\n")
+	assert.Contains(t, output, "hostname=sandboxed-runner\n")
+	assert.Contains(t, output, "input=hello\n")
+	assert.Contains(t, output, "root=bin data dev lib lib64 output proc tmp usr\n", "only mounts should be visible")
+	assert.Contains(t, output, "data=ro\n")
+	assert.Contains(t, output, "tmp=rw\n")
+
+	written, err := os.ReadFile(filepath.Join(outputDir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "written\n", string(written))
+}
+
+func TestSandboxInit(t *testing.T) {
+	sandbox := &config.SandboxConfig{ReadOnlyMounts: []string{"/bin", "/lib", "/lib64", "/usr"}}
+
+	t.Run("reaps orphaned processes", func(t *testing.T) {
+		output := runSandboxed(t, sandbox, "/", strings.Join([]string{
+			"sh -c 'sleep 0.1 &'",
+			"sleep 0.5",
+			"echo zombies=$(grep -l ') Z ' /proc/[0-9]*/stat | wc -l)",
+		}, "; "))
+
+		assert.Contains(t, output, "zombies=0\n")
+	})
+
+	t.Run("forwards signals to runner", func(t *testing.T) {
+		cmd := exec.Command("/bin/sh", "-c", "trap 'exit 3' TERM; echo ready; while :; do sleep 0.05; done")
+		cmd.Env = []string{"PATH=/usr/bin:/bin"}
+		stdout, err := cmd.StdoutPipe()
+		require.NoError(t, err)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		require.NoError(t, Wrap(cmd, Spec{Dir: "/", Sandbox: sandbox}))
+		require.NoError(t, cmd.Start())
+
+		ready := make([]byte, len("ready\n"))
+		if _, err := io.ReadFull(stdout, ready); err != nil {
+			_ = cmd.Wait()
+			t.Skipf("Namespaces are unavailable: %s", stderr.String())
+		}
+
+		// only the shim is signalled, as by SIGTERM sent to PID 1 of a container
+		require.NoError(t, cmd.Process.Signal(syscall.SIGTERM))
+
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+
+		select {
+		case err := <-done:
+			var exitErr *exec.ExitError
+			require.ErrorAs(t, err, &exitErr)
+			assert.Equal(t, 3, exitErr.ExitCode())
+		case <-time.After(2 * time.Second):
+			_ = cmd.Process.Kill()
+			t.Fatal("Expected runner to exit on SIGTERM to the shim")
+		}
+	})
+}
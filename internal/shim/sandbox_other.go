@@ -0,0 +1,23 @@
+//go:build !linux
+
+package shim
+
+import (
+	"errors"
+	"os/exec"
+	"task-runner-launcher/internal/config"
+)
+
+var errSandboxUnsupported = errors.New("sandbox is supported only on Linux")
+
+func prepareSandbox(_ *exec.Cmd) error {
+	return errSandboxUnsupported
+}
+
+func enterSandbox(_ *config.SandboxConfig) error {
+	return errSandboxUnsupported
+}
+
+func runInit(_ Spec) error {
+	return errSandboxUnsupported
+}
@@ -0,0 +1,128 @@
+package shim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/seccomp"
+)
+
+// envVarSpec is the env var through which the launcher passes the spec to the
+// shim. The shim removes it from the env before executing the runner.
+const envVarSpec = "N8N_RUNNERS_LAUNCHER_SHIM_SPEC"
+
+// ExitCodeSetupFailed is the exit code of the shim if it fails to set up the
+// runner process before executing the runner.
+const ExitCodeSetupFailed = 125
+
+// Spec describes how the shim is to set up the runner process before executing
+// the runner's command in its place.
+type Spec struct {
+	// Path is the path to the runner's command.
+	Path string `json:"path"`
+
+	// Dir is the dir to run the runner's command in.
+	Dir string `json:"dir,omitempty"`
+
+	// Rlimits are the resource limits to set, keyed by rlimit name.
+	Rlimits map[string]config.Rlimit `json:"rlimits,omitempty"`
+
+	// Sandbox is the namespace sandbox to run the runner in, if any.
+	Sandbox *config.SandboxConfig `json:"sandbox,omitempty"`
+
+	// Seccomp is the seccomp profile to apply to the runner, if any.
+	Seccomp *seccomp.Profile `json:"seccomp,omitempty"`
+
+	// SeccompLogOnly is whether to only log syscalls that the seccomp profile
+	// would deny.
+	SeccompLogOnly bool `json:"seccomp-log-only,omitempty"`
+}
+
+// Wrap makes the command start via the shim, i.e. the launcher's own binary,
+// which sets up the process as described by the spec and then executes the
+// command's original path with the command's args and env. Must be called after
+// setting the command's env and credential.
+func Wrap(cmd *exec.Cmd, spec Spec) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate launcher binary: %w", err)
+	}
+
+	if spec.Sandbox != nil {
+		if err := prepareSandbox(cmd); err != nil {
+			return err
+		}
+	}
+
+	spec.Path = cmd.Path
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to encode shim spec: %w", err)
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", envVarSpec, data))
+	cmd.Path = self
+
+	return nil
+}
+
+// IsShim returns whether the current process was started as a shim by `Wrap`.
+func IsShim() bool {
+	_, ok := os.LookupEnv(envVarSpec)
+	return ok
+}
+
+// Run sets up the current process as described by the spec passed in by the
+// launcher, then executes the runner's command in its place or, in a sandbox,
+// runs the runner as its child. Returns only on failure.
+func Run() error {
+	var spec Spec
+	if err := json.Unmarshal([]byte(os.Getenv(envVarSpec)), &spec); err != nil {
+		return fmt.Errorf("failed to decode shim spec: %w", err)
+	}
+
+	if err := os.Unsetenv(envVarSpec); err != nil {
+		return err
+	}
+
+	if spec.Sandbox != nil {
+		if err := enterSandbox(spec.Sandbox); err != nil {
+			return fmt.Errorf("failed to set up sandbox: %w", err)
+		}
+
+		// the shim stays as init of the sandbox's PID namespace, while a child
+		// shim sets up the runner process as usual
+		spec.Sandbox = nil
+		return runInit(spec)
+	}
+
+	if spec.Dir != "" {
+		if err := os.Chdir(spec.Dir); err != nil {
+			return err
+		}
+	}
+
+	if err := setRlimits(spec.Rlimits); err != nil {
+		return err
+	}
+
+	// applied last, as the profile may deny syscalls needed to set up the process
+	if spec.Seccomp != nil {
+		if err := spec.Seccomp.Apply(spec.SeccompLogOnly); err != nil {
+			return err
+		}
+	}
+
+	// #nosec G204 -- path is the runner command set by the system administrator
+	if err := syscall.Exec(spec.Path, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("failed to execute %s: %w", spec.Path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package shim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"task-runner-launcher/internal/config"
+	"task-runner-launcher/internal/seccomp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary act as the shim, as the launcher binary does.
+func TestMain(m *testing.M) {
+	if IsShim() {
+		err := Run()
+		fmt.Fprintf(os.Stderr, "Failed to set up runner process: %v\n", err)
+		os.Exit(ExitCodeSetupFailed)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestWrap(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "true")
+	cmd.Env = []string{"FOO=bar"}
+
+	require.NoError(t, Wrap(cmd, Spec{}))
+
+	self, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, self, cmd.Path)
+	assert.Equal(t, []string{"/bin/sh", "-c", "true"}, cmd.Args)
+	assert.Equal(t, "FOO=bar", cmd.Env[0])
+	assert.Equal(t, envVarSpec+`={"path":"/bin/sh"}`, cmd.Env[1])
+}
+
+func TestRunSetsRlimits(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "ulimit -n; ulimit -c; env")
+	cmd.Env = []string{"FOO=bar"}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	require.NoError(t, Wrap(cmd, Spec{Rlimits: map[string]config.Rlimit{
+		"RLIMIT_NOFILE": {Soft: 64, Hard: 64},
+		"RLIMIT_CORE":   {Soft: 0, Hard: 0},
+	}}))
+	require.NoError(t, cmd.Run())
+
+	output := stdout.String()
+	assert.Contains(t, output, "64\n0\n")
+	assert.Contains(t, output, "FOO=bar")
+	assert.NotContains(t, output, envVarSpec, "spec should not be passed on to runner")
+}
+
+func TestRunFailure(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "true")
+	cmd.Env = []string{}
+
+	require.NoError(t, Wrap(cmd, Spec{}))
+	cmd.Env[len(cmd.Env)-1] = envVarSpec + `={"path":"/nonexistent"}`
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, ExitCodeSetupFailed, exitErr.ExitCode())
+	assert.Contains(t, stderr.String(), "failed to execute /nonexistent")
+}
+
+func TestExceededRlimit(t *testing.T) {
+	tests := []struct {
+		name           string
+		script         string
+		sandboxed      bool
+		expectedRlimit string
+		expectedOk     bool
+	}{
+		{
+			name:           "cpu time limit",
+			script:         "kill -XCPU $$",
+			expectedRlimit: "RLIMIT_CPU",
+			expectedOk:     true,
+		},
+		{
+			name:           "file size limit",
+			script:         "kill -XFSZ $$",
+			expectedRlimit: "RLIMIT_FSIZE",
+			expectedOk:     true,
+		},
+		{
+			name:           "cpu time limit in sandbox",
+			script:         "kill -XCPU $$",
+			sandboxed:      true,
+			expectedRlimit: "RLIMIT_CPU",
+			expectedOk:     true,
+		},
+		{
+			name:       "other signal",
+			script:     "kill -TERM $$",
+			expectedOk: false,
+		},
+		{
+			name:       "exit code",
+			script:     "exit 1",
+			expectedOk: false,
+		},
+		{
+			name:           "exit code of sandbox init",
+			script:         "exit 152", // 128 + SIGXCPU
+			sandboxed:      true,
+			expectedRlimit: "RLIMIT_CPU",
+			expectedOk:     true,
+		},
+		{
+			name:       "exit code of unsandboxed runner",
+			script:     "exit 152", // runner's own exit code
+			expectedOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			_ = cmd.Run()
+
+			rlimit, ok := ExceededRlimit(cmd.ProcessState, tt.sandboxed)
+			assert.Equal(t, tt.expectedRlimit, rlimit)
+			assert.Equal(t, tt.expectedOk, ok)
+		})
+	}
+
+	_, ok := ExceededRlimit(nil, true)
+	assert.False(t, ok)
+}
+
+func TestRunAppliesSeccompProfile(t *testing.T) {
+	profile, err := seccomp.ParseProfile([]byte(
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mkdir", "mkdirat"], "action": "SCMP_ACT_ERRNO"}]}`,
+	))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		logOnly        bool
+		expectedOutput string
+	}{
+		{
+			name:           "enforcing",
+			logOnly:        false,
+			expectedOutput: "denied",
+		},
+		{
+			name:           "log-only",
+			logOnly:        true,
+			expectedOutput: "allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "dir")
+			cmd := exec.Command("/bin/sh", "-c", "mkdir "+dir+" 2>/dev/null && echo allowed || echo denied")
+			cmd.Env = []string{}
+			var stdout bytes.Buffer
+			cmd.Stdout = &stdout
+
+			require.NoError(t, Wrap(cmd, Spec{Seccomp: profile, SeccompLogOnly: tt.logOnly}))
+			require.NoError(t, cmd.Run())
+
+			assert.Equal(t, tt.expectedOutput+"\n", stdout.String())
+		})
+	}
+}
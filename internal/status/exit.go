@@ -0,0 +1,116 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// KillReason is why the launcher terminated a runner.
+type KillReason string
+
+const (
+	// KillReasonShutdown means the runner was terminated on launcher shutdown.
+	KillReasonShutdown KillReason = "shutdown"
+
+	// KillReasonUnhealthy means the runner was terminated on failing too many
+	// health checks in a row.
+	KillReasonUnhealthy KillReason = "unhealthy"
+
+	// KillReasonRecycled means the runner was drained on reaching its max lifetime.
+	KillReasonRecycled KillReason = "recycled"
+
+	// KillReasonExpired means the runner was terminated on reaching its max
+	// launch duration.
+	KillReasonExpired KillReason = "expired"
+
+	// KillReasonReloaded means the runner was terminated on a config reload that
+	// changed its runner config, so as to relaunch it with the new config.
+	KillReasonReloaded KillReason = "reloaded"
+
+	// KillReasonRestarted means the runner was terminated on a restart of its
+	// runner type via the admin API.
+	KillReasonRestarted KillReason = "restarted"
+
+	// KillReasonDrained means the runner was terminated on a drain of its runner
+	// type via the admin API.
+	KillReasonDrained KillReason = "drained"
+)
+
+// RunnerExit describes how a runner process exited.
+type RunnerExit struct {
+	// Exit code of the runner, or -1 if the runner was terminated by a signal.
+	ExitCode int `json:"exitCode"`
+
+	// Name of the signal that terminated the runner, e.g. `SIGKILL`, if any.
+	Signal string `json:"signal,omitempty"`
+
+	// Whether the launcher terminated the runner, and why.
+	KilledByLauncher bool       `json:"killedByLauncher"`
+	KillReason       KillReason `json:"killReason,omitempty"`
+
+	// Whether the kernel OOM-killed a process in the runner's cgroup.
+	OOMKilled bool `json:"oomKilled"`
+
+	// Resource limit that the runner was terminated on exceeding, e.g. `RLIMIT_CPU`, if any.
+	ExceededRlimit string `json:"exceededRlimit,omitempty"`
+
+	ExitedAt time.Time     `json:"exitedAt"`
+	Duration time.Duration `json:"-"`
+}
+
+// NewRunnerExit returns the exit of a runner process that started at the given
+// time, as reported by its process state.
+func NewRunnerExit(state *os.ProcessState, startedAt time.Time) RunnerExit {
+	exitedAt := time.Now()
+	exit := RunnerExit{
+		ExitCode: state.ExitCode(),
+		ExitedAt: exitedAt,
+		Duration: exitedAt.Sub(startedAt),
+	}
+
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		exit.Signal = unix.SignalName(ws.Signal())
+		if exit.Signal == "" {
+			exit.Signal = ws.Signal().String()
+		}
+	}
+
+	return exit
+}
+
+// Failed returns whether the runner exited with an error or was terminated
+// other than on launcher shutdown, recycling, config reload or an admin action.
+func (e RunnerExit) Failed() bool {
+	switch e.KillReason {
+	case KillReasonShutdown, KillReasonRecycled, KillReasonExpired, KillReasonReloaded, KillReasonRestarted, KillReasonDrained:
+		return false
+	case KillReasonUnhealthy:
+		return true
+	}
+
+	return e.ExitCode != 0 || e.Signal != "" || e.OOMKilled
+}
+
+// String describes the exit, e.g. `exit code 1 after 2.5s`.
+func (e RunnerExit) String() string {
+	duration := e.Duration.Round(time.Millisecond)
+	if e.Signal != "" {
+		return fmt.Sprintf("signal %s after %v", e.Signal, duration)
+	}
+
+	return fmt.Sprintf("exit code %d after %v", e.ExitCode, duration)
+}
+
+func (e RunnerExit) MarshalJSON() ([]byte, error) {
+	type alias RunnerExit
+
+	return json.Marshal(struct {
+		alias
+		DurationSeconds float64 `json:"durationSeconds"`
+	}{alias(e), e.Duration.Seconds()})
+}
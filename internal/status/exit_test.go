@@ -0,0 +1,98 @@
+package status
+
+import (
+	"encoding/json"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRunnerExit(t *testing.T) {
+	tests := []struct {
+		name             string
+		script           string
+		expectedExitCode int
+		expectedSignal   string
+	}{
+		{
+			name:             "exit code",
+			script:           "exit 3",
+			expectedExitCode: 3,
+		},
+		{
+			name:             "signal",
+			script:           "kill -SEGV $$",
+			expectedExitCode: -1,
+			expectedSignal:   "SIGSEGV",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sh", "-c", tt.script)
+			startedAt := time.Now()
+			require.Error(t, cmd.Run())
+
+			exit := NewRunnerExit(cmd.ProcessState, startedAt)
+
+			assert.Equal(t, tt.expectedExitCode, exit.ExitCode)
+			assert.Equal(t, tt.expectedSignal, exit.Signal)
+			assert.Positive(t, exit.Duration)
+			assert.True(t, exit.Failed())
+		})
+	}
+}
+
+func TestRunnerExitFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		exit     RunnerExit
+		expected bool
+	}{
+		{"clean exit", RunnerExit{ExitCode: 0}, false},
+		{"error exit", RunnerExit{ExitCode: 1}, true},
+		{"signal", RunnerExit{ExitCode: -1, Signal: "SIGSEGV"}, true},
+		{"killed on shutdown", RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: KillReasonShutdown}, false},
+		{"killed as unhealthy", RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: KillReasonUnhealthy}, true},
+		{"exited on SIGTERM as unhealthy", RunnerExit{ExitCode: 0, KilledByLauncher: true, KillReason: KillReasonUnhealthy}, true},
+		{"killed on config reload", RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: KillReasonReloaded}, false},
+		{"killed on restart", RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: KillReasonRestarted}, false},
+		{"exited on drain", RunnerExit{ExitCode: 0, KilledByLauncher: true, KillReason: KillReasonDrained}, false},
+		{"killed on recycling", RunnerExit{ExitCode: -1, Signal: "SIGKILL", KilledByLauncher: true, KillReason: KillReasonRecycled}, false},
+		{"OOM-killed descendant", RunnerExit{ExitCode: 0, OOMKilled: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.exit.Failed())
+		})
+	}
+}
+
+func TestRunnerExitJSON(t *testing.T) {
+	exit := RunnerExit{
+		ExitCode:         -1,
+		Signal:           "SIGKILL",
+		KilledByLauncher: true,
+		KillReason:       KillReasonUnhealthy,
+		ExitedAt:         time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Duration:         1500 * time.Millisecond,
+	}
+
+	data, err := json.Marshal(exit)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"exitCode": -1,
+		"signal": "SIGKILL",
+		"killedByLauncher": true,
+		"killReason": "unhealthy",
+		"oomKilled": false,
+		"exitedAt": "2025-01-01T00:00:00Z",
+		"durationSeconds": 1.5
+	}`, string(data))
+	assert.Equal(t, "signal SIGKILL after 1.5s", exit.String())
+}
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// State is what the launcher is doing for an instance of a runner type.
+type State string
+
+const (
+	// StateIdle means the instance is free, e.g. while another instance of the
+	// runner type has a task offer pending, or while the runner type is paused.
+	StateIdle State = "idle"
+
+	// StateBackingOff means the launcher is waiting to relaunch the runner type
+	// after a failed launch.
+	StateBackingOff State = "backing-off"
+
+	// StateWaitingForBroker means the launcher is waiting for the task broker to
+	// be ready.
+	StateWaitingForBroker State = "waiting-for-broker"
+
+	// StateHandshaking means the launcher is connecting to the task broker to
+	// send a task offer.
+	StateHandshaking State = "handshaking"
+
+	// StateOfferPending means the launcher's task offer is waiting to be accepted.
+	StateOfferPending State = "offer-pending"
+
+	// StateRunning means a runner is running as the instance.
+	StateRunning State = "running"
+
+	// StatePaused means the launcher is not launching runners of the runner type.
+	// Only reported for the runner type as a whole.
+	StatePaused State = "paused"
+)
+
+// statePrecedence orders instance states from the most to the least advanced,
+// for the state of a runner type to summarize the states of its instances.
+var statePrecedence = []State{
+	StateRunning,
+	StateOfferPending,
+	StateHandshaking,
+	StateWaitingForBroker,
+	StateBackingOff,
+}
+
+// Instance holds the status of an instance of a runner type.
+type Instance struct {
+	State State `json:"state"`
+
+	// Process ID and start time of the runner running as the instance, if any.
+	PID       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+
+	// URI of the task broker that the runner running as the instance, if any,
+	// is attached to.
+	Broker string `json:"broker,omitempty"`
+}
+
+func (i Instance) MarshalJSON() ([]byte, error) {
+	type alias Instance
+
+	var uptimeSeconds *float64
+	if i.StartedAt != nil {
+		uptime := time.Since(*i.StartedAt).Seconds()
+		uptimeSeconds = &uptime
+	}
+
+	return json.Marshal(struct {
+		alias
+		UptimeSeconds *float64 `json:"uptimeSeconds,omitempty"`
+	}{alias(i), uptimeSeconds})
+}
+
+// summarizeState returns the state of a runner type, i.e. `paused` if the runner
+// type is paused, else the most advanced state of its instances.
+func summarizeState(runner *Runner) State {
+	if runner.Paused {
+		return StatePaused
+	}
+
+	for _, state := range statePrecedence {
+		for _, instance := range runner.Instances {
+			if instance.State == state {
+				return state
+			}
+		}
+	}
+
+	return StateIdle
+}
@@ -0,0 +1,113 @@
+// Package status tracks the state of each runner type managed by the launcher,
+// as reported by the launcher's health check server.
+package status
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a runner type's crash-loop circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed means the runner type is launched as usual.
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen means the runner type has crashed too many times in a row, so
+	// the launcher stops offering tasks for it until a cool-down has passed.
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen means the cool-down has passed and the next launch decides
+	// whether the circuit closes again or reopens.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// Runner holds the status of a runner type.
+type Runner struct {
+	// What the launcher is doing for the runner type, summarizing its instances.
+	State State `json:"state"`
+
+	// Whether launching runners of the runner type is paused.
+	Paused bool `json:"paused"`
+
+	// Status of each instance of the runner type, by instance number.
+	Instances []Instance `json:"instances"`
+
+	// URI of the task broker that the runner type was most recently attached
+	// to, i.e. sent a task offer to or launched a runner for, if any.
+	Broker string `json:"broker,omitempty"`
+
+	Circuit             CircuitState `json:"circuit"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	CircuitOpenUntil    *time.Time   `json:"circuitOpenUntil,omitempty"`
+	LastExit            *RunnerExit  `json:"lastExit,omitempty"`
+
+	// Why the runner type's files failed their integrity check before the last
+	// launch, if they did.
+	IntegrityError string `json:"integrityError,omitempty"`
+}
+
+// Registry holds the status of every runner type, safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	runners map[string]*Runner
+}
+
+func NewRegistry() *Registry {
+	return &Registry{runners: make(map[string]*Runner)}
+}
+
+// Update applies the given function to the status of the runner type.
+func (r *Registry) Update(runnerType string, fn func(runner *Runner)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runner, ok := r.runners[runnerType]
+	if !ok {
+		runner = &Runner{State: StateIdle, Circuit: CircuitClosed}
+		r.runners[runnerType] = runner
+	}
+
+	fn(runner)
+	runner.State = summarizeState(runner)
+}
+
+// UpdateInstance applies the given function to the status of the given instance
+// of the runner type.
+func (r *Registry) UpdateInstance(runnerType string, instance int, fn func(instance *Instance)) {
+	r.Update(runnerType, func(runner *Runner) {
+		for len(runner.Instances) <= instance {
+			runner.Instances = append(runner.Instances, Instance{State: StateIdle})
+		}
+		fn(&runner.Instances[instance])
+	})
+}
+
+// Runners returns a copy of the status of every runner type.
+func (r *Registry) Runners() map[string]Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runners := make(map[string]Runner, len(r.runners))
+	for runnerType, runner := range r.runners {
+		copied := *runner
+		copied.Instances = slices.Clone(runner.Instances)
+		runners[runnerType] = copied
+	}
+
+	return runners
+}
+
+// IsDegraded returns whether the circuit of any runner type is not closed, or
+// any runner type failed its integrity check.
+func (r *Registry) IsDegraded() bool {
+	for _, runner := range r.Runners() {
+		if runner.Circuit != CircuitClosed || runner.IntegrityError != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package status
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.Update("javascript", func(runner *Runner) {
+		runner.ConsecutiveFailures = 2
+	})
+
+	runners := registry.Runners()
+	assert.Equal(t, Runner{State: StateIdle, Circuit: CircuitClosed, ConsecutiveFailures: 2}, runners["javascript"])
+	assert.False(t, registry.IsDegraded())
+
+	runners["javascript"] = Runner{Circuit: CircuitOpen}
+	assert.Equal(t, CircuitClosed, registry.Runners()["javascript"].Circuit, "returned status should be a copy")
+
+	registry.Update("python", func(runner *Runner) {
+		runner.Circuit = CircuitHalfOpen
+	})
+	assert.True(t, registry.IsDegraded())
+
+	registry.Update("python", func(runner *Runner) {
+		runner.Circuit = CircuitClosed
+		runner.IntegrityError = "command: sha256 mismatch"
+	})
+	assert.True(t, registry.IsDegraded())
+}
+
+func TestRegistryInstances(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.UpdateInstance("javascript", 1, func(instance *Instance) {
+		instance.State = StateOfferPending
+	})
+	runner := registry.Runners()["javascript"]
+	assert.Equal(t, []Instance{{State: StateIdle}, {State: StateOfferPending}}, runner.Instances)
+	assert.Equal(t, StateOfferPending, runner.State)
+
+	startedAt := time.Now()
+	registry.UpdateInstance("javascript", 0, func(instance *Instance) {
+		*instance = Instance{State: StateRunning, PID: 42, StartedAt: &startedAt}
+	})
+	assert.Equal(t, StateRunning, registry.Runners()["javascript"].State, "running instance should take precedence")
+
+	runners := registry.Runners()
+	runners["javascript"].Instances[0].PID = 0
+	assert.Equal(t, 42, registry.Runners()["javascript"].Instances[0].PID, "returned instances should be a copy")
+
+	registry.Update("javascript", func(runner *Runner) {
+		runner.Paused = true
+	})
+	assert.Equal(t, StatePaused, registry.Runners()["javascript"].State)
+}
+
+func TestInstanceJSON(t *testing.T) {
+	startedAt := time.Now().Add(-90 * time.Second)
+	data, err := json.Marshal(Instance{State: StateRunning, PID: 42, StartedAt: &startedAt})
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "running", decoded["state"])
+	assert.InDelta(t, 42, decoded["pid"], 0)
+	assert.InDelta(t, 90, decoded["uptimeSeconds"], 5)
+
+	data, err = json.Marshal(Instance{State: StateIdle})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"state": "idle"}`, string(data))
+}
@@ -1,12 +1,18 @@
 package ws
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
+	"task-runner-launcher/internal/broker"
 	"task-runner-launcher/internal/errs"
 	"task-runner-launcher/internal/logs"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -18,6 +24,9 @@ const (
 	msgBrokerInfoRequest      = "broker:inforequest"
 	msgBrokerRunnerRegistered = "broker:runnerregistered"
 	msgBrokerTaskOfferAccept  = "broker:taskofferaccept"
+
+	// closeTimeout is the max time to wait for a close frame to be sent.
+	closeTimeout = 1 * time.Second
 )
 
 type message struct {
@@ -34,6 +43,13 @@ type HandshakeConfig struct {
 	TaskType            string
 	TaskBrokerServerURI string
 	GrantToken          string
+
+	// Transport to connect to the task broker with. Optional.
+	Transport *broker.Transport
+
+	// OnOfferSent is called once the task offer has been sent, i.e. while the
+	// offer is waiting to be accepted. Optional.
+	OnOfferSent func()
 }
 
 func validateConfig(cfg HandshakeConfig) error {
@@ -53,7 +69,7 @@ func validateConfig(cfg HandshakeConfig) error {
 }
 
 func buildWebsocketURL(taskBrokerServerURI, runnerID string) (*url.URL, error) {
-	u, err := url.Parse(taskBrokerServerURI)
+	u, err := url.Parse(broker.HTTPURI(taskBrokerServerURI))
 	if err != nil {
 		return nil, fmt.Errorf("invalid task broker URI: %w", err)
 	}
@@ -62,7 +78,12 @@ func buildWebsocketURL(taskBrokerServerURI, runnerID string) (*url.URL, error) {
 		return nil, fmt.Errorf("task broker URI must have no query params")
 	}
 
-	u.Scheme = "ws"
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
 	u.Path = "/runners/_ws"
 
 	q := u.Query()
@@ -72,18 +93,21 @@ func buildWebsocketURL(taskBrokerServerURI, runnerID string) (*url.URL, error) {
 	return u, nil
 }
 
-func connectToWebsocket(wsURL *url.URL, grantToken string, logger *logs.Logger) (*websocket.Conn, error) {
+func connectToWebsocket(ctx context.Context, transport *broker.Transport, wsURL *url.URL, grantToken string, logger *logs.Logger) (*websocket.Conn, error) {
 	reqHeader := map[string][]string{
 		"Authorization": {fmt.Sprintf("Bearer %s", grantToken)},
 	}
 
-	dialer := websocket.Dialer{
-		ReadBufferSize:  512,
-		WriteBufferSize: 512,
-	}
+	dialer := transport.Dialer()
+	dialer.ReadBufferSize = 512
+	dialer.WriteBufferSize = 512
 
-	wsConn, _, err := dialer.Dial(wsURL.String(), reqHeader)
+	wsConn, resp, err := dialer.DialContext(ctx, wsURL.String(), reqHeader)
 	if err != nil {
+		if resp == nil {
+			// no response at all, so the task broker is unreachable
+			return nil, fmt.Errorf("%w: websocket connection failed: %w", errs.ErrServerDown, err)
+		}
 		return nil, fmt.Errorf("websocket connection failed: %w", err)
 	}
 
@@ -103,11 +127,27 @@ func isWsCloseError(err error) bool {
 	return ok
 }
 
+// isConnError returns whether the error is due to the connection to the task
+// broker breaking without a close frame, e.g. on a reset or EOF.
+func isConnError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// closeGracefully sends a close frame to the task broker before closing the
+// connection, so that the broker can discard the launcher's pending task offer.
+func closeGracefully(wsConn *websocket.Conn) {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "launcher shutting down")
+	_ = wsConn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout))
+	wsConn.Close()
+}
+
 // Handshake is the flow where the launcher connects via websocket with task broker,
 // registers, sends a non-expiring task offer, and receives the accept for that
 // offer. Note that the handshake completes only once this task offer is accepted,
-// which may take time.
-func Handshake(cfg HandshakeConfig, logger *logs.Logger) error {
+// which may take time. If the context is cancelled before then, the connection
+// is closed cleanly and the context error is returned.
+func Handshake(ctx context.Context, cfg HandshakeConfig, logger *logs.Logger) error {
 	if err := validateConfig(cfg); err != nil {
 		return fmt.Errorf("received invalid handshake config: %w", err)
 	}
@@ -120,12 +160,12 @@ func Handshake(cfg HandshakeConfig, logger *logs.Logger) error {
 		return fmt.Errorf("failed to build websocket URL: %w", err)
 	}
 
-	wsConn, err := connectToWebsocket(wsURL, cfg.GrantToken, logger)
+	wsConn, err := connectToWebsocket(ctx, cfg.Transport, wsURL, cfg.GrantToken, logger)
 	if err != nil {
 		return err
 	}
 
-	errReceived := make(chan error)
+	errReceived := make(chan error, 1)
 	handshakeComplete := make(chan struct{})
 
 	go func() {
@@ -136,7 +176,7 @@ func Handshake(cfg HandshakeConfig, logger *logs.Logger) error {
 			err := wsConn.ReadJSON(&msg)
 			if err != nil {
 				switch {
-				case isWsCloseError(err):
+				case isWsCloseError(err), isConnError(err):
 					errReceived <- errs.ErrServerDown
 				case err == websocket.ErrReadLimit:
 					errReceived <- errs.ErrWsMsgTooLarge
@@ -178,6 +218,10 @@ func Handshake(cfg HandshakeConfig, logger *logs.Logger) error {
 				logger.Debugf("-> Sent message `%s` for offer ID `%s`", msg.Type, msg.OfferID)
 				logger.Info("Waiting for launcher's task offer to be accepted...")
 
+				if cfg.OnOfferSent != nil {
+					cfg.OnOfferSent()
+				}
+
 			case msgBrokerTaskOfferAccept:
 				msg := message{
 					Type:   msgRunnerTaskDeferred,
@@ -203,6 +247,10 @@ func Handshake(cfg HandshakeConfig, logger *logs.Logger) error {
 	}()
 
 	select {
+	case <-ctx.Done():
+		closeGracefully(wsConn)
+		logger.Debugf("Disconnected: %s", wsURL.String())
+		return ctx.Err()
 	case err := <-errReceived:
 		wsConn.Close()
 		return err
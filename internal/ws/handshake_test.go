@@ -1,10 +1,15 @@
 package ws
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"syscall"
 	"task-runner-launcher/internal/errs"
 	"task-runner-launcher/internal/logs"
 	"testing"
@@ -117,6 +122,15 @@ func TestHandshake(t *testing.T) {
 			},
 			expectedError: errs.ErrServerDown.Error(),
 		},
+		{
+			name: "server unreachable",
+			config: HandshakeConfig{
+				TaskType:            "javascript",
+				TaskBrokerServerURI: "http://localhost:1",
+				GrantToken:          "test-token",
+			},
+			expectedError: errs.ErrServerDown.Error(),
+		},
 	}
 
 	for _, tt := range tests {
@@ -144,19 +158,67 @@ func TestHandshake(t *testing.T) {
 				tt.config.TaskBrokerServerURI = "http://" + server.Listener.Addr().String()
 			}
 
+			offerSent := false
+			tt.config.OnOfferSent = func() { offerSent = true }
+
 			logger := logs.NewLogger(logs.InfoLevel, "")
-			err := Handshake(tt.config, logger)
+			err := Handshake(context.Background(), tt.config, logger)
 
 			if tt.expectedError != "" {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedError)
+				assert.False(t, offerSent)
 			} else {
 				assert.NoError(t, err)
+				assert.True(t, offerSent)
 			}
 		})
 	}
 }
 
+func TestBuildWebsocketURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		uri           string
+		expectedURL   string
+		expectedError string
+	}{
+		{
+			name:        "http maps to ws",
+			uri:         "http://127.0.0.1:5679",
+			expectedURL: "ws://127.0.0.1:5679/runners/_ws?id=abc",
+		},
+		{
+			name:        "https maps to wss",
+			uri:         "https://broker.example.com",
+			expectedURL: "wss://broker.example.com/runners/_ws?id=abc",
+		},
+		{
+			name:        "unix socket maps to placeholder host",
+			uri:         "unix:///run/n8n/broker.sock",
+			expectedURL: "ws://localhost/runners/_ws?id=abc",
+		},
+		{
+			name:          "query params",
+			uri:           "https://broker.example.com?foo=bar",
+			expectedError: "task broker URI must have no query params",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := buildWebsocketURL(tt.uri, "abc")
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedURL, u.String())
+		})
+	}
+}
+
 func TestRandomID(t *testing.T) {
 	seen := make(map[string]bool)
 	iterations := 1000
@@ -200,6 +262,41 @@ func TestIsWsCloseError(t *testing.T) {
 	}
 }
 
+func TestIsConnError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "connection reset",
+			err:      &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+			expected: true,
+		},
+		{
+			name:     "unexpected EOF",
+			err:      fmt.Errorf("failed to read: %w", io.ErrUnexpectedEOF),
+			expected: true,
+		},
+		{
+			name:     "other error",
+			err:      errors.New("error other than connection error"),
+			expected: false,
+		},
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isConnError(tt.err), "Unexpected result for isConnError")
+		})
+	}
+}
+
 func TestHandshakeTimeout(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
@@ -222,7 +319,7 @@ func TestHandshakeTimeout(t *testing.T) {
 	done := make(chan error)
 	go func() {
 		logger := logs.NewLogger(logs.InfoLevel, "")
-		done <- Handshake(HandshakeConfig{
+		done <- Handshake(context.Background(), HandshakeConfig{
 			TaskType:            "javascript",
 			TaskBrokerServerURI: "http://" + srv.Listener.Addr().String(),
 			GrantToken:          "test-token",
@@ -236,3 +333,50 @@ func TestHandshakeTimeout(t *testing.T) {
 		t.Error("Test timed out")
 	}
 }
+
+func TestHandshakeCancellation(t *testing.T) {
+	closeReceived := make(chan int, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err, "Failed to upgrade connection")
+		defer conn.Close()
+
+		err = conn.WriteJSON(message{Type: msgBrokerInfoRequest})
+		require.NoError(t, err, "Failed to write `broker:inforequest`")
+
+		var msg message
+		require.NoError(t, conn.ReadJSON(&msg), "Failed to read `runner:info`")
+
+		err = conn.WriteJSON(message{Type: msgBrokerRunnerRegistered})
+		require.NoError(t, err, "Failed to write `broker:runnerregistered`")
+
+		require.NoError(t, conn.ReadJSON(&msg), "Failed to read `runner:taskoffer`")
+
+		_, _, err = conn.ReadMessage()
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			closeReceived <- closeErr.Code
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	logger := logs.NewLogger(logs.InfoLevel, "")
+	err := Handshake(ctx, HandshakeConfig{
+		TaskType:            "javascript",
+		TaskBrokerServerURI: "http://" + srv.Listener.Addr().String(),
+		GrantToken:          "test-token",
+	}, logger)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Expected cancellation error")
+
+	select {
+	case code := <-closeReceived:
+		assert.Equal(t, websocket.CloseGoingAway, code, "Unexpected close code")
+	case <-time.After(time.Second):
+		t.Error("Expected broker to receive close frame")
+	}
+}
@@ -0,0 +1,50 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"task-runner-launcher/internal/broker"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/logs"
+)
+
+// Probe connects to the task broker's websocket endpoint with the given grant
+// token and waits for the broker to request the launcher's info, confirming
+// that the broker accepted the grant token. Probe then disconnects without
+// registering, so it neither takes up a runner slot nor sends a task offer.
+func Probe(ctx context.Context, transport *broker.Transport, taskBrokerServerURI, grantToken string, logger *logs.Logger) error {
+	wsURL, err := buildWebsocketURL(taskBrokerServerURI, randomID())
+	if err != nil {
+		return fmt.Errorf("failed to build websocket URL: %w", err)
+	}
+
+	wsConn, err := connectToWebsocket(ctx, transport, wsURL, grantToken, logger)
+	if err != nil {
+		return err
+	}
+	defer closeGracefully(wsConn)
+
+	// unblock the read below on cancellation
+	stop := context.AfterFunc(ctx, func() { wsConn.Close() })
+	defer stop()
+
+	var msg message
+	if err := wsConn.ReadJSON(&msg); err != nil {
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case isWsCloseError(err), isConnError(err):
+			return errs.ErrServerDown
+		default:
+			return fmt.Errorf("failed to read ws message: %w", err)
+		}
+	}
+
+	logger.Debugf("<- Received message `%s`", msg.Type)
+
+	if msg.Type != msgBrokerInfoRequest {
+		return fmt.Errorf("expected message `%s` but received `%s`", msgBrokerInfoRequest, msg.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"task-runner-launcher/internal/errs"
+	"task-runner-launcher/internal/logs"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name          string
+		handlerFunc   func(*testing.T, *websocket.Conn)
+		expectedError string
+	}{
+		{
+			name: "broker requests info",
+			handlerFunc: func(t *testing.T, conn *websocket.Conn) {
+				require.NoError(t, conn.WriteJSON(message{Type: msgBrokerInfoRequest}))
+
+				// launcher disconnects without registering
+				_, _, err := conn.ReadMessage()
+				var closeErr *websocket.CloseError
+				require.ErrorAs(t, err, &closeErr)
+				assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+			},
+		},
+		{
+			name: "broker closes connection",
+			handlerFunc: func(t *testing.T, conn *websocket.Conn) {
+				msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "unauthorized")
+				require.NoError(t, conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)))
+			},
+			expectedError: errs.ErrServerDown.Error(),
+		},
+		{
+			name: "unexpected message",
+			handlerFunc: func(t *testing.T, conn *websocket.Conn) {
+				require.NoError(t, conn.WriteJSON(message{Type: msgBrokerRunnerRegistered}))
+			},
+			expectedError: "expected message `broker:inforequest` but received `broker:runnerregistered`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(handled)
+
+				assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+
+				conn, err := upgrader.Upgrade(w, r, nil)
+				require.NoError(t, err, "Failed to upgrade connection")
+				defer conn.Close()
+
+				tt.handlerFunc(t, conn)
+			}))
+			defer srv.Close()
+
+			logger := logs.NewLogger(logs.InfoLevel, "")
+			err := Probe(context.Background(), nil, srv.URL, "test-token", logger)
+
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedError)
+			}
+
+			<-handled
+		})
+	}
+}
+
+func TestProbeCancellation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err, "Failed to upgrade connection")
+		defer conn.Close()
+
+		_, _, _ = conn.ReadMessage() // never request info
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := Probe(ctx, nil, srv.URL, "test-token", logs.NewLogger(logs.InfoLevel, ""))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}